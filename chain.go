@@ -0,0 +1,99 @@
+package cron
+
+import "runtime/debug"
+
+// JobWrapper decorates the given Job with some behavior.
+type JobWrapper func(Job) Job
+
+// Chain is a sequence of JobWrappers that decorates submitted jobs with
+// cross-cutting behaviors like logging or synchronization.
+type Chain struct {
+	wrappers []JobWrapper
+}
+
+// NewChain returns a Chain consisting of the given JobWrappers.
+func NewChain(c ...JobWrapper) Chain {
+	return Chain{c}
+}
+
+// Then decorates the given job with all JobWrappers in the chain.
+//
+// The first wrapper is the outermost one, e.g.
+//
+//	NewChain(m1, m2, m3).Then(job)
+//
+// is equivalent to:
+//
+//	m1(m2(m3(job)))
+//
+// The decorated job of NewContextJob or NewErrJob still runs with the
+// context of its entry in Cron.
+func (c Chain) Then(j Job) Job {
+	for i := len(c.wrappers) - 1; i >= 0; i-- {
+		inner, ok := j.(contextRunner)
+		j = c.wrappers[i](j)
+		if _, forwarded := j.(contextRunner); ok && !forwarded {
+			j = &wrappedContextJob{j, inner.contextJob()}
+		}
+	}
+	return j
+}
+
+// wrappedContextJob is a job decorated by a wrapper, which forwards the
+// contextJob it runs.
+type wrappedContextJob struct {
+	Job
+	inner *contextJob
+}
+
+func (j *wrappedContextJob) contextJob() *contextJob { return j.inner }
+
+// Recover panics in wrapped jobs and log them with the provided logger.
+func Recover(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		return FuncJob(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("Job panic: %v\n%s", r, debug.Stack())
+				}
+			}()
+			j.Run()
+		})
+	}
+}
+
+// SkipIfStillRunning skips an invocation of the Job if a previous invocation is
+// still running. It logs skips to the given logger.
+func SkipIfStillRunning(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		ch := make(chan struct{}, 1)
+		ch <- struct{}{}
+		return FuncJob(func() {
+			select {
+			case v := <-ch:
+				defer func() { ch <- v }()
+				j.Run()
+			default:
+				logger.Info("Skip job, the previous one is still running")
+			}
+		})
+	}
+}
+
+// DelayIfStillRunning serializes jobs, delaying subsequent runs until the
+// previous one is complete. It logs the delay to the given logger.
+func DelayIfStillRunning(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		ch := make(chan struct{}, 1)
+		return FuncJob(func() {
+			select {
+			case ch <- struct{}{}:
+			default:
+				logger.Info("Delay job, the previous one is still running")
+				ch <- struct{}{}
+			}
+			defer func() { <-ch }()
+			j.Run()
+		})
+	}
+}
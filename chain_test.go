@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func appendingWrapper(slice *[]string, name string) JobWrapper {
+	return func(j Job) Job {
+		return FuncJob(func() {
+			*slice = append(*slice, name)
+			j.Run()
+		})
+	}
+}
+
+func TestChainThen(t *testing.T) {
+	var nums []string
+	job := FuncJob(func() { nums = append(nums, "job") })
+	NewChain(
+		appendingWrapper(&nums, "1"),
+		appendingWrapper(&nums, "2"),
+		appendingWrapper(&nums, "3"),
+	).Then(job).Run()
+	if result := strings.Join(nums, ","); result != "1,2,3,job" {
+		t.Fatalf("NewChain(1, 2, 3).Then(job).Run() => 1,2,3,job, but got %s", result)
+	}
+}
+
+func TestChainRecover(t *testing.T) {
+	NewChain(Recover(printfLogger(testPrintf{t}))).Then(FuncJob(func() {
+		panic("boom")
+	})).Run()
+}
+
+func TestChainSkipIfStillRunning(t *testing.T) {
+	var (
+		count   int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	job := NewChain(SkipIfStillRunning(printfLogger(testPrintf{t}))).Then(FuncJob(func() {
+		atomic.AddInt32(&count, 1)
+		<-release
+	}))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		job.Run()
+	}()
+	// wait the first one to run
+	for atomic.LoadInt32(&count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	job.Run()
+	job.Run()
+	close(release)
+	wg.Wait()
+	job.Run()
+	if count != 2 {
+		t.Fatalf("SkipIfStillRunning runs => 2 times, but got %d", count)
+	}
+}
+
+func TestChainDelayIfStillRunning(t *testing.T) {
+	var (
+		running int32
+		count   int32
+		wg      sync.WaitGroup
+	)
+	job := NewChain(DelayIfStillRunning(printfLogger(testPrintf{t}))).Then(FuncJob(func() {
+		if atomic.AddInt32(&running, 1) > 1 {
+			t.Errorf("DelayIfStillRunning runs concurrently")
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&count, 1)
+		atomic.AddInt32(&running, -1)
+	}))
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run()
+		}()
+	}
+	wg.Wait()
+	if count != 3 {
+		t.Fatalf("DelayIfStillRunning runs => 3 times, but got %d", count)
+	}
+}
@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ContextJob is an interface for submitted Cron jobs which accept a context,
+// it is added to Cron by NewContextJob.
+//
+// The context is cancelled if the Cron stops or releases, the entry is
+// removed, or the timeout of the entry is reached. It carries the entry-id
+// and the scheduled time of the job, see EntryIDFromContext and
+// ScheduledFromContext.
+type ContextJob interface {
+	Run(ctx context.Context)
+}
+
+// ContextFuncJob a func implement ContextJob interface.
+type ContextFuncJob func(ctx context.Context)
+
+// Run wrapper func
+func (f ContextFuncJob) Run(ctx context.Context) { f(ctx) }
+
+// ErrJob is a ContextJob which returns an error, it is added to Cron by NewErrJob.
+//
+// The error is logged and recorded by its entry, see Entry.LastError.
+type ErrJob interface {
+	Run(ctx context.Context) error
+}
+
+// ErrFuncJob a func implement ErrJob interface.
+type ErrFuncJob func(ctx context.Context) error
+
+// Run wrapper func
+func (f ErrFuncJob) Run(ctx context.Context) error { return f(ctx) }
+
+type (
+	entryIDKey   struct{}
+	scheduledKey struct{}
+)
+
+// EntryIDFromContext returns the entry-id carried by the context of a ContextJob.
+func EntryIDFromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(entryIDKey{}).(int)
+	return id, ok
+}
+
+// ScheduledFromContext returns the scheduled time carried by the context of a ContextJob.
+func ScheduledFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(scheduledKey{}).(time.Time)
+	return t, ok
+}
+
+// NewContextJob adapts a ContextJob to Job.
+//
+// Cron runs it with the context of its entry, otherwise it runs with
+// context.Background. It could be decorated by Chain.Then before it is
+// added to Cron, but not by calling a JobWrapper directly, which hides
+// it from Cron.
+func NewContextJob(job ContextJob) Job {
+	return NewErrJob(ErrFuncJob(func(ctx context.Context) error {
+		job.Run(ctx)
+		return nil
+	}))
+}
+
+// NewErrJob adapts an ErrJob to Job like NewContextJob.
+//
+// Cron records the error in the run of its entry, otherwise it is dropped.
+func NewErrJob(job ErrJob) Job {
+	return &contextJob{job: job}
+}
+
+// contextRunner is a Job which runs a contextJob, the wrappers decorating
+// it by Chain.Then forward the contextJob.
+type contextRunner interface {
+	Job
+	contextJob() *contextJob
+}
+
+// runCall is the argument of a run of contextJob in Cron, which is passed
+// through the wrappers of the job.
+type runCall struct {
+	ctx context.Context
+	err error
+}
+
+// contextJob adapts an ErrJob to Job.
+//
+// Cron passes the call of a run to it by enter, before the run goes through
+// the wrappers. The next run enters after the job takes the call, or the
+// run leaves without taking it, so that the runs do not mix up their calls
+// without holding each other while the job runs.
+type contextJob struct {
+	job ErrJob
+
+	handoff sync.Mutex // held from a run enters until its call is taken
+	mu      sync.Mutex
+	call    *runCall // the call of the entered run
+}
+
+func (j *contextJob) contextJob() *contextJob { return j }
+
+func (j *contextJob) Run() {
+	call := j.take()
+	if call == nil {
+		// it runs out of Cron, or by a wrapper out of its run
+		j.job.Run(context.Background())
+		return
+	}
+	err := j.job.Run(call.ctx)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	call.err = err
+}
+
+// enter passes call to the next Run.
+func (j *contextJob) enter(call *runCall) {
+	j.handoff.Lock()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.call = call
+}
+
+// take takes the call of the entered run, if any.
+func (j *contextJob) take() *runCall {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	call := j.call
+	if call != nil {
+		j.call = nil
+		j.handoff.Unlock()
+	}
+	return call
+}
+
+// leave ends the run of call, and returns the error returned by the job.
+// The call is dropped if it is not taken, e.g. the run is skipped.
+func (j *contextJob) leave(call *runCall) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.call == call {
+		j.call = nil
+		j.handoff.Unlock()
+	}
+	return call.err
+}
@@ -0,0 +1,242 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContextJobValues(t *testing.T) {
+	type values struct {
+		id        int
+		scheduled time.Time
+	}
+	result := make(chan values, 1)
+	h := New(WithLogger(printfLogger(testPrintf{t})))
+	id := h.Add("@yearly", NewContextJob(ContextFuncJob(func(ctx context.Context) {
+		id, _ := EntryIDFromContext(ctx)
+		scheduled, _ := ScheduledFromContext(ctx)
+		result <- values{id, scheduled}
+	})), WithEntryRunFirst())
+	begin := time.Now()
+	go h.Run()
+	defer h.Stop()
+	select {
+	case v := <-result:
+		if v.id != id || v.scheduled.Before(begin.Truncate(time.Second)) {
+			t.Fatalf("context of job => (%d, >= %v), but got (%d, %v)", id, begin, v.id, v.scheduled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("job is not called")
+	}
+}
+
+func TestContextJobScheduled(t *testing.T) {
+	datas := []struct {
+		name    string
+		wrapper JobWrapper
+	}{
+		{"concurrent", Recover(printfLogger(testPrintf{t}))},
+		{"delayed", DelayIfStillRunning(printfLogger(testPrintf{t}))},
+	}
+	for _, data := range datas {
+		var (
+			mu        sync.Mutex
+			scheduled = make(map[time.Time]int)
+		)
+		h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+		h.Add("20ms", NewContextJob(ContextFuncJob(func(ctx context.Context) {
+			t, _ := ScheduledFromContext(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			scheduled[t]++
+		})), WithEntryMisfire(MisfireFireAll, time.Millisecond), WithEntryChain(data.wrapper))
+		go h.Run()
+		time.Sleep(5 * time.Millisecond)
+		h.Stop()
+		// the missed times run with their own scheduled time
+		time.Sleep(100 * time.Millisecond)
+		go h.Run()
+		time.Sleep(5 * time.Millisecond)
+		h.Shutdown(context.Background())
+
+		mu.Lock()
+		if len(scheduled) < 4 {
+			t.Fatalf("%s: scheduled times of 4 or more missed runs => distinct, but got %v", data.name, scheduled)
+		}
+		for s, n := range scheduled {
+			if n != 1 {
+				t.Fatalf("%s: scheduled time %v => 1 run, but got %d", data.name, s, n)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+func TestWrappedContextJob(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		values = make(map[int]time.Time)
+	)
+	failure := errors.New("failure")
+	h := New(WithLogger(printfLogger(testPrintf{t})))
+	id := h.Add("@yearly", NewChain(Recover(printfLogger(testPrintf{t}))).Then(NewErrJob(ErrFuncJob(func(ctx context.Context) error {
+		id, _ := EntryIDFromContext(ctx)
+		scheduled, _ := ScheduledFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		values[id] = scheduled
+		return failure
+	}))), WithEntryRunFirst())
+	go h.Run()
+	time.Sleep(10 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(values) != 1 || values[id].IsZero() {
+		t.Fatalf("context of job decorated by Chain.Then => entry %d with scheduled time, but got %v", id, values)
+	}
+	if e, _ := h.Entry(id); !errors.Is(e.LastError(), failure) {
+		t.Fatalf("Entry(%d).LastError() => %v, but got %v", id, failure, e.LastError())
+	}
+}
+
+func TestContextJobCancel(t *testing.T) {
+	datas := []struct {
+		name    string
+		opts    []EntryOption
+		cancel  func(h Cron, id int)
+		timeout bool
+	}{
+		{
+			name:   "Remove",
+			cancel: func(h Cron, id int) { h.Remove(id) },
+		},
+		{
+			name:   "Stop",
+			cancel: func(h Cron, id int) { h.Stop() },
+		},
+		{
+			name:   "Release",
+			cancel: func(h Cron, id int) { h.Release() },
+		},
+		{
+			name:    "Timeout",
+			opts:    []EntryOption{WithEntryTimeout(10 * time.Millisecond)},
+			cancel:  func(h Cron, id int) {},
+			timeout: true,
+		},
+	}
+	for _, data := range datas {
+		var (
+			started = make(chan struct{})
+			result  = make(chan error, 1)
+		)
+		h := New(WithLogger(printfLogger(testPrintf{t})))
+		opts := append([]EntryOption{WithEntryRunFirst()}, data.opts...)
+		id := h.Add("@yearly", NewContextJob(ContextFuncJob(func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			result <- ctx.Err()
+		})), opts...)
+		go h.Run()
+		<-started
+		data.cancel(h, id)
+		select {
+		case err := <-result:
+			if data.timeout != errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("%s => context error (timeout: %v), but got %v", data.name, data.timeout, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s => context is cancelled, but not", data.name)
+		}
+		h.Stop()
+	}
+}
+
+func TestAddInvalidJob(t *testing.T) {
+	h := New()
+	if id, err := h.AddE("@yearly", nil); id != 0 || !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("AddE with nil job => (0, %v), but got (%d, %v)", ErrInvalidJob, id, err)
+	}
+}
+
+func TestErrJob(t *testing.T) {
+	var (
+		failing = int32(1)
+		failure = errors.New("failure")
+		logs    = &strings.Builder{}
+		mu      sync.Mutex
+	)
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(printfFunc(func(format string, params ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(logs, format+"\n", params...)
+	}))))
+	id := h.Add("5ms", NewErrJob(ErrFuncJob(func(ctx context.Context) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return failure
+		}
+		return nil
+	})))
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(30 * time.Millisecond)
+
+	e, _ := h.Entry(id)
+	if !errors.Is(e.LastError(), failure) || e.Failures() < 2 || !e.LastSuccess().IsZero() {
+		t.Fatalf("Entry(%d) => (%v, >= 2 failures, no success), but got (%v, %d, %v)", id, failure, e.LastError(), e.Failures(), e.LastSuccess())
+	}
+	if history := e.History(); len(history) < 2 || !errors.Is(history[0].Err, failure) {
+		t.Fatalf("Entry(%d).History() => runs with %v, but got %+v", id, failure, history)
+	}
+	mu.Lock()
+	if !strings.Contains(logs.String(), "Job error, id: 1, spec: 5ms: failure") {
+		t.Fatalf("logs => contains the job error, but got %s", logs.String())
+	}
+	mu.Unlock()
+
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(30 * time.Millisecond)
+	if !errors.Is(e.LastError(), failure) || e.Failures() != 0 || e.LastSuccess().IsZero() {
+		t.Fatalf("Entry(%d) => (%v, 0 failures, success), but got (%v, %d, %v)", id, failure, e.LastError(), e.Failures(), e.LastSuccess())
+	}
+}
+
+// printfFunc a func implement the Printf of logger.
+type printfFunc func(format string, params ...interface{})
+
+func (f printfFunc) Printf(format string, params ...interface{}) { f(format, params...) }
+
+func TestErrJobRecords(t *testing.T) {
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.Add("20ms", NewErrJob(ErrFuncJob(func(ctx context.Context) error {
+		scheduled, _ := ScheduledFromContext(ctx)
+		return errors.New(scheduled.String())
+	})), WithEntryMisfire(MisfireFireAll, time.Millisecond))
+	go h.Run()
+	time.Sleep(5 * time.Millisecond)
+	h.Stop()
+	// the missed times run concurrently, each error is recorded in its run
+	time.Sleep(100 * time.Millisecond)
+	go h.Run()
+	time.Sleep(5 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	e, _ := h.Entry(id)
+	history := e.History()
+	if len(history) < 4 {
+		t.Fatalf("Entry(%d).History() => 4 or more runs, but got %+v", id, history)
+	}
+	for _, r := range history {
+		if r.Err == nil || r.Err.Error() != r.Scheduled.String() {
+			t.Fatalf("run scheduled at %v => error of it, but got %v", r.Scheduled, r.Err)
+		}
+	}
+}
@@ -1,17 +1,61 @@
 package cron
 
+import "context"
+
 // Cron interface
 type Cron interface {
 	// Add adds a job to the Cron to be run on the given schedule.
 	Add(spec string, job Job, opts ...EntryOption) int
 	// AddFunc adds a func to the Cron to be run on the given schedule.
 	AddFunc(spec string, fn func(), opts ...EntryOption) int
+	// AddE adds a job to the Cron to be run on the given schedule,
+	// or returns the error if the spec or the job is invalid.
+	AddE(spec string, job Job, opts ...EntryOption) (int, error)
+	// AddFuncE adds a func to the Cron to be run on the given schedule,
+	// or returns the error if the spec is invalid.
+	AddFuncE(spec string, fn func(), opts ...EntryOption) (int, error)
+	// AddOrReplace adds a job with the name to the Cron,
+	// or replaces the entry with the name if it exists.
+	AddOrReplace(name, spec string, job Job, opts ...EntryOption) (int, error)
 	// Remove an entry with entry-id.
 	Remove(id int)
+	// RemoveByName removes an entry with the name.
+	RemoveByName(name string)
+	// RemoveWhere removes the entries matched by sel, and returns their entry-ids.
+	RemoveWhere(sel Selector) []int
+	// Update the spec of an entry with entry-id, and apply opts to it.
+	// The entry keeps its id and counts.
+	Update(id int, spec string, opts ...EntryOption) error
+	// Trigger runs the job of an entry with entry-id now, without changing its next time.
+	// It returns ErrSkipped if the job is skipped by the overlap policy of entry.
+	Trigger(id int) error
+	// Pause an entry with entry-id, it stays in the Cron but does not run.
+	Pause(id int)
+	// Resume a paused entry with entry-id, the next time is calculated from now.
+	Resume(id int)
+	// PauseWhere pauses the entries matched by sel, and returns their entry-ids.
+	PauseWhere(sel Selector) []int
+	// ResumeWhere resumes the paused entries matched by sel, and returns their entry-ids.
+	ResumeWhere(sel Selector) []int
+	// Entries returns the snapshots of all entries, ordered by the next time.
+	Entries() []Entry
+	// EntriesWhere returns the snapshots of the entries matched by sel, ordered by the next time,
+	// the retries of entries are not matched.
+	EntriesWhere(sel Selector) []Entry
+	// Entry returns the snapshot of an entry with entry-id.
+	Entry(id int) (Entry, bool)
+	// EntryByName returns the snapshot of an entry with the name.
+	EntryByName(name string) (Entry, bool)
+	// Restore the entries saved in the Store, see WithStore.
+	// It should be called before Run.
+	Restore() error
 	// Run the Cron in synchronous mode, or no-op if alreay running.
 	Run()
 	// Stop the Cron if it is running, otherwise no-op.
 	Stop()
+	// Shutdown stops the Cron, and waits for the running jobs to finish
+	// until ctx is done. It returns the entry-ids of the jobs still running.
+	Shutdown(ctx context.Context) ([]int, error)
 	// Release all entry in Cron.
 	Release()
 }
@@ -0,0 +1,134 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLockTTL is the ttl of the locks of firings if it is not set.
+const DefaultLockTTL = time.Minute
+
+// DistributedLocker locks the firings of entries among the replicas of Cron,
+// so that each firing runs once in the cluster, see WithDistributedLocker.
+//
+// It is distinct from the SpinLock, which is local to a Cron.
+type DistributedLocker interface {
+	// TryLock acquires the lock of key which expires after ttl,
+	// and reports whether it is acquired.
+	//
+	// The lock is never released before it expires, so that the replicas
+	// late for the firing do not run it again.
+	TryLock(key string, ttl time.Duration) (bool, error)
+}
+
+// lockKey returns the key of the firing of entry scheduled at the given time.
+func lockKey(name string, scheduled time.Time) string {
+	return name + "@" + scheduled.UTC().Format(time.RFC3339Nano)
+}
+
+// FileLocker is a DistributedLocker with the files in a directory,
+// which is shared by the replicas on a host or a network file system.
+//
+// It is safe for concurrent use by multiple goroutines and processes.
+type FileLocker struct {
+	dir string
+
+	mu      sync.Mutex
+	cleaned time.Time // the time of the last cleanup
+}
+
+// NewFileLocker returns a FileLocker in dir, the dir is created if it does not exist.
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileLocker{dir: dir}, nil
+}
+
+// TryLock creates the lock file of key exclusively, which contains the expiry time.
+//
+// The expiry time is written to a temporary file which is linked to the lock
+// file, so that the lock file is never seen without it.
+// The expired lock files are removed at most once per ttl.
+func (l *FileLocker) TryLock(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	l.cleanup(now, ttl)
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	f, err := os.CreateTemp(l.dir, name+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(now.Add(ttl).UTC().Format(time.RFC3339Nano))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, err
+	}
+	err = os.Link(f.Name(), filepath.Join(l.dir, name+".lock"))
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cleanup removes the expired lock files, and the temporary files left by
+// the processes died in TryLock.
+//
+// The expiry time of a file without a valid one is its modification time
+// plus ttl.
+func (l *FileLocker) cleanup(now time.Time, ttl time.Duration) {
+	l.mu.Lock()
+	if now.Sub(l.cleaned) < ttl {
+		l.mu.Unlock()
+		return
+	}
+	l.cleaned = now
+	l.mu.Unlock()
+
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(l.dir, file.Name())
+		var expiry time.Time
+		switch {
+		case strings.HasSuffix(file.Name(), ".lock"):
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if expiry, err = time.Parse(time.RFC3339Nano, string(data)); err == nil {
+				break
+			}
+			fallthrough
+		case strings.HasSuffix(file.Name(), ".tmp"):
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			expiry = info.ModTime().Add(ttl)
+		default:
+			continue
+		}
+		if expiry.After(now) {
+			continue
+		}
+		os.Remove(path)
+	}
+}
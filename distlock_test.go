@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileLocker(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewFileLocker(dir)
+	if err != nil {
+		t.Fatalf("NewFileLocker(%s) failure: %s", dir, err)
+	}
+	scheduled := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	datas := []struct {
+		key      string
+		expected bool
+	}{
+		{lockKey("report", scheduled), true},
+		{lockKey("report", scheduled), false},
+		{lockKey("report", scheduled.In(time.FixedZone("UTC+8", 8*3600))), false},
+		{lockKey("report", scheduled.Add(time.Minute)), true},
+		{lockKey("clean", scheduled), true},
+	}
+	for _, data := range datas {
+		if ok, err := l.TryLock(data.key, time.Hour); err != nil || ok != data.expected {
+			t.Fatalf("TryLock(%s) => (%v, nil), but got (%v, %v)", data.key, data.expected, ok, err)
+		}
+	}
+
+	// the expired locks are removed
+	expired, _ := NewFileLocker(dir)
+	expired.TryLock(lockKey("temp", scheduled), -time.Second)
+	time.Sleep(time.Millisecond)
+	expired.TryLock(lockKey("next", scheduled), time.Millisecond)
+	files, _ := os.ReadDir(dir)
+	if len(files) != 4 {
+		t.Fatalf("lock files => 4, but got %d", len(files))
+	}
+
+	// the files left by the processes died in TryLock are removed after ttl
+	dir = t.TempDir()
+	stale := time.Now().Add(-time.Hour)
+	for _, name := range []string{"empty.lock", "left.0.tmp"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, nil, 0o644)
+		os.Chtimes(path, stale, stale)
+	}
+	l, _ = NewFileLocker(dir)
+	if ok, err := l.TryLock(lockKey("report", scheduled), time.Minute); !ok || err != nil {
+		t.Fatalf("TryLock(%s) => (true, nil), but got (%v, %v)", lockKey("report", scheduled), ok, err)
+	}
+	files, _ = os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("lock files => 1, but got %d", len(files))
+	}
+}
+
+// alignedSchedule is due at the multiples of duration, it is the same among replicas.
+type alignedSchedule time.Duration
+
+func (s alignedSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(s)).Add(time.Duration(s))
+}
+
+// alignedParser parses the spec as the duration of alignedSchedule.
+type alignedParser struct{}
+
+func (alignedParser) Parse(spec string) (Schedule, error) {
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, err
+	}
+	return alignedSchedule(d), nil
+}
+
+func TestDistributedLocker(t *testing.T) {
+	l, err := NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileLocker failure: %s", err)
+	}
+	var (
+		mu   sync.Mutex
+		runs = make(map[time.Time]int)
+	)
+	job := NewContextJob(ContextFuncJob(func(ctx context.Context) {
+		scheduled, _ := ScheduledFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		runs[scheduled]++
+	}))
+	var replicas []Cron
+	for i := 0; i < 3; i++ {
+		h := New(WithParser(alignedParser{}), WithLogger(printfLogger(testPrintf{t})), WithDistributedLocker(l, time.Second))
+		h.Add("10ms", job, WithEntryName("job"))
+		replicas = append(replicas, h)
+	}
+	for _, h := range replicas {
+		go h.Run()
+	}
+	time.Sleep(55 * time.Millisecond)
+	for _, h := range replicas {
+		h.Shutdown(context.Background())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(runs) < 3 {
+		t.Fatalf("firings => >= 3, but got %d", len(runs))
+	}
+	for scheduled, n := range runs {
+		if n != 1 {
+			t.Fatalf("firing at %v runs => 1 time, but got %d", scheduled, n)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package cron
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 )
 
@@ -18,14 +21,291 @@ func (f FuncJob) Run() { f() }
 // Entry the minimum task unit of Cron.
 type Entry struct {
 	ID       int
+	Name     string            // The unique name in Cron, optional
+	Labels   map[string]string // The labels to select entries, it must not be modified
 	Spec     string
 	Job      Job
+	JobType  string // The type of job in Registry, see WithStore
 	Schedule Schedule
 	Next     time.Time
 	Prev     time.Time
 	Times    uint // The max Execute times
 	count    uint // already running count
 	RunFirst bool
+	Overlap  OverlapPolicy // What to do if the previous job is still running
+	Timeout  time.Duration // Cancel the context of ContextJob and report after the timeout
+	Paused   bool          // The paused entry does not run, see Cron.Pause
+	Retry    RetryPolicy   // How to retry the failed job
+
+	Misfire          MisfirePolicy // What to do if the job of entry misfires
+	MisfireThreshold time.Duration // How late the job misfires, default is DefaultMisfireThreshold
+	CatchUp          int           // The max count of runs to catch up on Cron.Run, see WithEntryCatchUp
+
+	CountTrigger bool // Whether Cron.Trigger counts toward the max execute times
+
+	skipped   uint // the count of jobs skipped by OverlapSkip or OverlapQueue
+	queued    uint // the count of jobs queued by OverlapQueue
+	triggered uint // the count of jobs started by Cron.Trigger
+
+	retryOf int // the entry-id of the failed entry, if it is a retry
+	attempt int // the attempt of the retry, the first run is 1
+
+	wrappers   []JobWrapper // The wrappers of the entry, inside the wrappers of Cron
+	wrappedJob Job          // The Job decorated by all wrappers
+	state      *entryState
+}
+
+// entryState is the state of an entry shared with its running jobs.
+type entryState struct {
+	running int32         // the count of running jobs, accessed atomically
+	serial  chan struct{} // serializes the jobs queued by OverlapQueue
+
+	mu      sync.Mutex
+	ctx     context.Context // cancelled if the Cron stops or the entry is removed
+	cancel  context.CancelFunc
+	history []*RunRecord // the recent runs, the latest is the last
+
+	lastErr     error     // the error of the last failed run
+	lastSuccess time.Time // the end time of the last succeeded run
+	failures    uint      // the count of consecutive failed runs
+}
+
+// maxHistory is the max count of run records kept by an entry.
+const maxHistory = 10
+
+// RunRecord is the record of a run of the job.
+type RunRecord struct {
+	Scheduled time.Time   // The scheduled time
+	Start     time.Time   // The time the job starts
+	End       time.Time   // The time the job ends, zero if it is still running
+	TimedOut  bool        // Whether the job exceeded the timeout of entry
+	Panic     interface{} // The recovered panic of the job, if any
+	Err       error       // The error returned by the ErrJob, if any
+}
+
+// Duration returns the duration of the run, or zero if it is still running.
+func (r RunRecord) Duration() time.Duration {
+	if r.End.IsZero() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+func newEntryState() *entryState {
+	return &entryState{
+		serial: make(chan struct{}, 1),
+	}
+}
+
+// reset derives the context of entry from parent, and cancels the previous one.
+func (s *entryState) reset(parent context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.ctx, s.cancel = context.WithCancel(parent)
+}
+
+// close cancels the context of entry.
+func (s *entryState) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// context returns the context of entry.
+func (s *entryState) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// begin records a run of the job, and returns the record to update.
+func (s *entryState) begin(scheduled, start time.Time) *RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &RunRecord{
+		Scheduled: scheduled,
+		Start:     start,
+	}
+	if len(s.history) >= maxHistory {
+		s.history = append(s.history[:0], s.history[1:]...)
+	}
+	s.history = append(s.history, r)
+	return r
+}
+
+// update updates the record of a run of the job.
+func (s *entryState) update(r *RunRecord, fn func(r *RunRecord)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(r)
+}
+
+// end records the end of a run of the job with its error and panic,
+// and returns the copy of the record.
+//
+// The run fails if the job returns an error or panics.
+func (s *entryState) end(r *RunRecord, end time.Time, err error, recovered interface{}) RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.End = end
+	r.Err = err
+	r.Panic = recovered
+	switch {
+	case r.Err != nil:
+		s.lastErr = r.Err
+		s.failures++
+	case recovered != nil:
+		s.lastErr = fmt.Errorf("Job panic: %v", recovered)
+		s.failures++
+	default:
+		s.lastSuccess = end
+		s.failures = 0
+	}
+	return *r
+}
+
+// records returns the copies of recent run records.
+func (s *entryState) records() []RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]RunRecord, len(s.history))
+	for i, r := range s.history {
+		records[i] = *r
+	}
+	return records
+}
+
+// restore restores the counts, times and paused state of entry from the record.
+//
+// The next time is restored if the Cron is not running, and the spec is not changed.
+func (e *Entry) restore(r EntryRecord, running bool) {
+	e.Prev = r.Prev
+	e.count = r.Count
+	e.Paused = r.Paused
+	if e.Paused {
+		e.Next = time.Time{}
+	} else if !running && e.Spec == r.Spec {
+		e.Next = r.Next
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	if r.LastSuccess.After(e.state.lastSuccess) {
+		e.state.lastSuccess = r.LastSuccess
+	}
+}
+
+// Count returns the count of started jobs.
+func (e Entry) Count() uint { return e.count }
+
+// Skipped returns the count of jobs skipped by OverlapSkip or OverlapQueue.
+func (e Entry) Skipped() uint { return e.skipped }
+
+// Queued returns the count of jobs queued by OverlapQueue.
+func (e Entry) Queued() uint { return e.queued }
+
+// Triggered returns the count of jobs started by Cron.Trigger.
+func (e Entry) Triggered() uint { return e.triggered }
+
+// LastError returns the error of the last failed run, which is the error
+// returned by the ErrJob or the panic of the job. It is kept after success.
+func (e Entry) LastError() error {
+	if e.state == nil {
+		return nil
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.lastErr
+}
+
+// LastSuccess returns the end time of the last succeeded run.
+func (e Entry) LastSuccess() time.Time {
+	if e.state == nil {
+		return time.Time{}
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.lastSuccess
+}
+
+// Failures returns the count of consecutive failed runs.
+func (e Entry) Failures() uint {
+	if e.state == nil {
+		return 0
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.failures
+}
+
+// RetryOf returns the entry-id of the failed entry if it is a retry, otherwise 0.
+//
+// A retry is a one-shot entry in Cron, it runs the job of the failed entry
+// and shares its name, labels and history. Its job is reported to the
+// listeners with the entry-id of the failed entry, and it is not matched
+// by the selectors of Cron.
+func (e Entry) RetryOf() int { return e.retryOf }
+
+// Attempt returns the attempt of the retry, or 1 if it is not a retry.
+func (e Entry) Attempt() int {
+	if e.attempt == 0 {
+		return 1
+	}
+	return e.attempt
+}
+
+// History returns the recent runs of the job, the latest is the last.
+func (e Entry) History() []RunRecord {
+	if e.state == nil {
+		return nil
+	}
+	return e.state.records()
+}
+
+// OverlapPolicy decides what to do if the job of an entry is due,
+// but the previous one is still running.
+type OverlapPolicy int
+
+const (
+	// OverlapAllow runs the jobs concurrently.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip skips the new job.
+	OverlapSkip
+	// OverlapQueue runs the new job after the running one finishes.
+	// At most one job is queued, the others are skipped.
+	OverlapQueue
+)
+
+// MisfirePolicy decides what to do if the job of an entry misfires,
+// i.e. it is due for longer than the misfire threshold, because the Cron
+// is stopped or delayed.
+type MisfirePolicy int
+
+const (
+	// MisfireFireOnce runs the job once now for all the missed times.
+	MisfireFireOnce MisfirePolicy = iota
+	// MisfireFireAll runs the job for every missed time now, at most 100 times.
+	MisfireFireAll
+	// MisfireSkip skips the missed times, and waits for the next time.
+	MisfireSkip
+)
+
+// DefaultMisfireThreshold is the misfire threshold of entry if it is not set.
+const DefaultMisfireThreshold = time.Second
+
+// misfireThreshold returns the misfire threshold of entry.
+func (e *Entry) misfireThreshold() time.Duration {
+	if e.MisfireThreshold <= 0 {
+		return DefaultMisfireThreshold
+	}
+	return e.MisfireThreshold
 }
 
 type EntryOption func(e *Entry)
@@ -43,3 +323,137 @@ func WithEntryRunFirst() EntryOption {
 		e.RunFirst = true
 	}
 }
+
+// WithEntryChain decorates the job of entry with wrappers.
+//
+// They are applied inside the wrappers of Cron, see WithChain.
+func WithEntryChain(wrappers ...JobWrapper) EntryOption {
+	return func(e *Entry) {
+		e.wrappers = append(e.wrappers, wrappers...)
+	}
+}
+
+// WithEntryMisfire set the policy if the job of entry is due for longer than threshold.
+//
+// Default is MisfireFireOnce. The threshold is DefaultMisfireThreshold if it is not positive.
+func WithEntryMisfire(policy MisfirePolicy, threshold time.Duration) EntryOption {
+	return func(e *Entry) {
+		e.Misfire = policy
+		e.MisfireThreshold = threshold
+	}
+}
+
+// WithEntryCatchUp runs the job at most times when the entry is scheduled
+// at first, for the times missed since its last success, or its previous
+// time if it never succeeds. The missed times are enumerated by its
+// schedule from the earliest.
+//
+// It is used with WithEntryLastSuccess to catch up the runs missed while
+// the process restarts.
+func WithEntryCatchUp(times int) EntryOption {
+	return func(e *Entry) {
+		e.CatchUp = times
+	}
+}
+
+// WithEntryJobType set the type of job in Registry.
+//
+// The entry with a name and a job type is saved in the Store, see WithStore.
+func WithEntryJobType(jobType string) EntryOption {
+	return func(e *Entry) {
+		e.JobType = jobType
+	}
+}
+
+// WithEntryLastSuccess set the end time of the last succeeded run of entry,
+// which is usually stored before the process restarts.
+func WithEntryLastSuccess(t time.Time) EntryOption {
+	return func(e *Entry) {
+		if e.state != nil {
+			e.state.mu.Lock()
+			defer e.state.mu.Unlock()
+			e.state.lastSuccess = t
+		}
+	}
+}
+
+// WithEntryOverlap set the policy if the previous job of entry is still running.
+//
+// Default is OverlapAllow.
+func WithEntryOverlap(policy OverlapPolicy) EntryOption {
+	return func(e *Entry) {
+		e.Overlap = policy
+	}
+}
+
+// WithEntryTimeout cancel the context of ContextJob after the timeout.
+//
+// The job exceeded the timeout is logged and marked in the history of entry,
+// whether it is a ContextJob or not.
+func WithEntryTimeout(timeout time.Duration) EntryOption {
+	return func(e *Entry) {
+		e.Timeout = timeout
+	}
+}
+
+// WithEntryJob replace the job of entry, see Cron.Update.
+func WithEntryJob(job Job) EntryOption {
+	return func(e *Entry) {
+		e.Job = job
+	}
+}
+
+// WithEntryCountTrigger count the jobs started by Cron.Trigger toward the max execute times.
+func WithEntryCountTrigger() EntryOption {
+	return func(e *Entry) {
+		e.CountTrigger = true
+	}
+}
+
+// WithEntryName set the name of entry, which is unique in Cron.
+func WithEntryName(name string) EntryOption {
+	return func(e *Entry) {
+		e.Name = name
+	}
+}
+
+// WithEntryLabels set the labels of entry, which are used to select entries.
+func WithEntryLabels(labels map[string]string) EntryOption {
+	return func(e *Entry) {
+		e.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			e.Labels[k] = v
+		}
+	}
+}
+
+// Selector reports whether an entry is selected.
+//
+// It is called with the exclusive access to the entries of Cron,
+// so it must not call the methods of Cron.
+type Selector func(e Entry) bool
+
+// MatchLabels returns a Selector which selects the entries with all the labels.
+func MatchLabels(labels map[string]string) Selector {
+	return func(e Entry) bool {
+		for k, v := range labels {
+			if l, ok := e.Labels[k]; !ok || l != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WithEntryRetry retries the failed job up to maxAttempts attempts,
+// including the first one, waiting the delay of backoff before each retry.
+//
+// The retry is skipped if it would not run before the next time of entry.
+func WithEntryRetry(maxAttempts int, backoff Backoff) EntryOption {
+	return func(e *Entry) {
+		e.Retry = RetryPolicy{
+			MaxAttempts: maxAttempts,
+			Backoff:     backoff,
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidJob the job added to Cron is nil.
+var ErrInvalidJob = errors.New("Invalid job, it must not be nil")
+
+// ErrEntryNotFound the entry with entry-id is not in Cron.
+var ErrEntryNotFound = errors.New("Entry not found")
+
+// ErrDuplicateName the name of entry is already used by another entry in Cron.
+var ErrDuplicateName = errors.New("Duplicate entry name")
+
+// ErrInvalidName the name of entry is invalid.
+var ErrInvalidName = errors.New("Invalid entry name")
+
+// ErrNoStore the Cron has no Store, see WithStore.
+var ErrNoStore = errors.New("No store")
+
+// ErrUnknownJobType the job type is not registered in Registry.
+var ErrUnknownJobType = errors.New("Unknown job type")
+
+// ErrNotRunning the operation requires the Cron to be running.
+var ErrNotRunning = errors.New("Cron is not running")
+
+// ErrSkipped the job is skipped by the overlap policy of entry, see WithEntryOverlap.
+var ErrSkipped = errors.New("Job is skipped, the previous one is still running")
+
+// ParseErrorKind classifies a ParseError.
+type ParseErrorKind int
+
+const (
+	// KindSyntax the expression is malformed, e.g. too many hyphens.
+	KindSyntax ParseErrorKind = iota + 1
+	// KindOutOfRange the value is beyond the allowed range of the field.
+	KindOutOfRange
+	// KindBadRange the beginning of range is beyond the end of range.
+	KindBadRange
+	// KindBadStep the step is not a positive integer.
+	KindBadStep
+	// KindUnknownName the value is neither an integer nor a known name.
+	KindUnknownName
+	// KindFieldCount the number of fields is not accepted by the parser.
+	KindFieldCount
+	// KindDescriptor the descriptor is unknown, invalid or not accepted.
+	KindDescriptor
+	// KindTimeZone the time zone of CRON_TZ= or TZ= is invalid.
+	KindTimeZone
+)
+
+func (k ParseErrorKind) String() string {
+	switch k {
+	case KindSyntax:
+		return "syntax"
+	case KindOutOfRange:
+		return "out of range"
+	case KindBadRange:
+		return "bad range"
+	case KindBadStep:
+		return "bad step"
+	case KindUnknownName:
+		return "unknown name"
+	case KindFieldCount:
+		return "wrong field count"
+	case KindDescriptor:
+		return "bad descriptor"
+	case KindTimeZone:
+		return "bad time zone"
+	}
+	return fmt.Sprintf("ParseErrorKind(%d)", int(k))
+}
+
+// ParseError describes an invalid part of a spec.
+type ParseError struct {
+	Kind   ParseErrorKind
+	Spec   string      // The whole spec
+	Field  ParseOption // The field, e.g. Minute. Zero if the error is not in a field
+	Token  string      // The invalid part of the spec
+	Column int         // The byte offset of Token in Spec
+	Min    uint        // The min allowed value of the field
+	Max    uint        // The max allowed value of the field
+	Err    error       // The underlying error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == 0 {
+		return fmt.Sprintf("Invalid spec %q at column %d (%s): %s", e.Spec, e.Column, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("Invalid %s field %q in spec %q at column %d (%s), allowed range is [%d, %d]: %s",
+		e.Field, e.Token, e.Spec, e.Column, e.Kind, e.Min, e.Max, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseErrorf returns a ParseError of kind with a formatted underlying error.
+func parseErrorf(kind ParseErrorKind, format string, params ...interface{}) *ParseError {
+	return &ParseError{
+		Kind: kind,
+		Err:  fmt.Errorf(format, params...),
+	}
+}
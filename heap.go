@@ -2,12 +2,20 @@ package cron
 
 import (
 	"container/heap"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const defaultWaitTime = 1000000 * time.Hour
 
+// maxMisfires is the max count of missed times handled per firing.
+const maxMisfires = 100
+
 // Heap is minimal heap to implement Cron.
 //
 // It is safe for concurrent use by multiple goroutines.
@@ -19,65 +27,181 @@ type Heap struct {
 	remove  chan int
 	stop    chan struct{}
 	release chan struct{}
+	exec    chan func()
 	lock    sync.Locker
 
-	parser Parser
-	lastID int
-	logger Logger
+	parser   Parser
+	lastID   int
+	names    map[string]int // entry-id by the name of entry
+	logger   Logger
+	location *time.Location
+
+	chain        Chain
+	panicHandler func(entry Entry, recovered interface{})
+	jobs         *jobTracker
+	listener     multiListener
+
+	store    Store
+	registry *Registry
+	writer   *storeWriter
+	synced   bool // whether the entries are saved in store, after Restore or Run
+
+	distLocker DistributedLocker
+	lockTTL    time.Duration
+
+	ctx    context.Context // cancelled if the Cron stops
+	cancel context.CancelFunc
 }
 
 // New return a Cron implement in min-heap.
 //
 // It is faster than simple array when adding tasks dynamically.
 func New(opts ...Option) Cron {
-	// TODO: localtime
 	h := &Heap{
 		add:     make(chan *Entry),
 		remove:  make(chan int),
 		stop:    make(chan struct{}),
 		release: make(chan struct{}),
+		exec:    make(chan func()),
 		lock:    NewSpinLock(),
 		// lock: &sync.Mutex{},
-		parser: NewParser(ParseOptionStandard),
-		logger: defaultPrintLogger,
+		parser:   NewParser(ParseOptionStandard),
+		logger:   defaultPrintLogger,
+		location: time.Local,
+		jobs:     newJobTracker(),
+		names:    make(map[string]int),
 	}
 	for _, opt := range opts {
 		opt(h)
 	}
+	if h.store != nil {
+		h.writer = newStoreWriter(h.store, h.logger)
+	}
 	return h
 }
 
 // Add adds a job to the Cron to be run on the given schedule.
+//
+// A ContextJob or an ErrJob is added by NewContextJob or NewErrJob.
 func (h *Heap) Add(spec string, job Job, opts ...EntryOption) int {
-	schedule, err := h.parser.Parse(spec)
+	id, err := h.AddE(spec, job, opts...)
 	if err != nil {
 		h.logger.Error("Add job failure: %s", err)
-		return 0
+	}
+	return id
+}
+
+// AddFunc adds a func to the Cron to be run on the given schedule.
+func (h *Heap) AddFunc(spec string, fn func(), opts ...EntryOption) int {
+	return h.Add(spec, FuncJob(fn), opts...)
+}
+
+// AddE adds a job to the Cron to be run on the given schedule,
+// or returns the error if the spec or the job is invalid,
+// or the name of entry is already used.
+func (h *Heap) AddE(spec string, job Job, opts ...EntryOption) (int, error) {
+	if err := checkJob(job); err != nil {
+		return 0, err
+	}
+	schedule, err := h.parser.Parse(spec)
+	if err != nil {
+		return 0, err
 	}
 	entry := &Entry{
 		Spec:     spec,
 		Schedule: schedule,
 		Job:      job,
+		state:    newEntryState(),
 	}
 	for _, opt := range opts {
 		opt(entry)
 	}
 	h.lock.Lock()
 	defer h.lock.Unlock()
+	if entry.Name != "" {
+		var used bool
+		h.doLocked(func() { _, used = h.names[entry.Name] })
+		if used {
+			return 0, fmt.Errorf("%w: %s", ErrDuplicateName, entry.Name)
+		}
+	}
 	h.lastID++
 	id := h.lastID
 	entry.ID = id
+	entry.wrappedJob = h.wrap(entry)
 	if h.running {
 		h.add <- entry
 	} else {
-		heap.Push(&h.entries, entry)
+		h.pushEntry(entry)
 	}
-	return id
+	return id, nil
 }
 
-// AddFunc adds a func to the Cron to be run on the given schedule.
-func (h *Heap) AddFunc(spec string, fn func(), opts ...EntryOption) int {
-	return h.Add(spec, FuncJob(fn), opts...)
+// AddFuncE adds a func to the Cron to be run on the given schedule,
+// or returns the error if the spec is invalid.
+func (h *Heap) AddFuncE(spec string, fn func(), opts ...EntryOption) (int, error) {
+	return h.AddE(spec, FuncJob(fn), opts...)
+}
+
+// AddOrReplace adds a job with the name to the Cron like AddE,
+// or replaces the entry with the name if it exists.
+//
+// The replaced entry keeps its id and counts, and its next time if the spec
+// is not changed, so that it is safe to call it again with the same arguments.
+func (h *Heap) AddOrReplace(name, spec string, job Job, opts ...EntryOption) (int, error) {
+	if name == "" {
+		return 0, fmt.Errorf("%w: empty name", ErrInvalidName)
+	}
+	if err := checkJob(job); err != nil {
+		return 0, err
+	}
+	schedule, err := h.parser.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	entry := &Entry{
+		Spec:     spec,
+		Schedule: schedule,
+		Job:      job,
+		state:    newEntryState(),
+	}
+	for _, opt := range opts {
+		opt(entry)
+	}
+	entry.Name = name
+	var id int
+	h.do(func() {
+		var ok bool
+		if id, ok = h.names[name]; !ok {
+			h.lastID++
+			id = h.lastID
+			entry.ID = id
+			entry.wrappedJob = h.wrap(entry)
+			h.pushEntry(entry)
+			return
+		}
+		i, e := h.findEntry(id)
+		entry.ID = e.ID
+		entry.Next = e.Next
+		entry.Prev = e.Prev
+		entry.Paused = e.Paused
+		entry.count = e.count
+		entry.skipped = e.skipped
+		entry.queued = e.queued
+		entry.triggered = e.triggered
+		entry.state = e.state
+		if !entry.Paused && entry.Spec != e.Spec {
+			entry.Next = time.Time{}
+			if h.running {
+				entry.Next = entry.Schedule.Next(h.now())
+			}
+		}
+		*e = *entry
+		e.wrappedJob = h.wrap(e)
+		heap.Fix(&h.entries, i)
+		h.save(e)
+	})
+	return id, nil
 }
 
 // Remove an entry with entry-id.
@@ -91,6 +215,362 @@ func (h *Heap) Remove(id int) {
 	}
 }
 
+// RemoveByName removes an entry with the name.
+func (h *Heap) RemoveByName(name string) {
+	h.do(func() {
+		if id, ok := h.names[name]; ok {
+			h.removeEntry(id)
+		}
+	})
+}
+
+// Update the spec of an entry with entry-id, and apply opts to it.
+//
+// The entry keeps its id and counts, the next time is calculated from now.
+// The job could be replaced by WithEntryJob.
+func (h *Heap) Update(id int, spec string, opts ...EntryOption) error {
+	schedule, err := h.parser.Parse(spec)
+	if err != nil {
+		return err
+	}
+	h.do(func() {
+		i, e := h.findEntry(id)
+		if e == nil {
+			err = fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+			return
+		}
+		updated := *e
+		updated.Spec = spec
+		updated.Schedule = schedule
+		for _, opt := range opts {
+			opt(&updated)
+		}
+		if err = checkJob(updated.Job); err != nil {
+			return
+		}
+		if updated.Name != e.Name {
+			if _, used := h.names[updated.Name]; used {
+				err = fmt.Errorf("%w: %s", ErrDuplicateName, updated.Name)
+				return
+			}
+			h.unname(e)
+			h.unsave(e)
+		}
+		*e = updated
+		if e.Name != "" {
+			h.names[e.Name] = e.ID
+		}
+		e.wrappedJob = h.wrap(e)
+		if h.running && !e.Paused {
+			e.Next = e.Schedule.Next(h.now())
+			heap.Fix(&h.entries, i)
+		} else if !h.running {
+			// the next time of the previous spec does not misfire
+			e.Next = time.Time{}
+		}
+		h.save(e)
+	})
+	return err
+}
+
+// Trigger runs the job of an entry with entry-id now, without changing its
+// next time. It follows the overlap policy of entry, and counts toward the
+// max execute times only if the entry is added with WithEntryCountTrigger.
+// It returns ErrSkipped if the job is skipped by the overlap policy.
+func (h *Heap) Trigger(id int) error {
+	var err error
+	h.do(func() {
+		if !h.running {
+			err = ErrNotRunning
+			return
+		}
+		i, e := h.findEntry(id)
+		if e == nil {
+			err = fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+			return
+		}
+		if !h.startJob(e, h.now()) {
+			err = fmt.Errorf("%w: %d", ErrSkipped, id)
+			return
+		}
+		e.triggered++
+		if !e.CountTrigger {
+			return
+		}
+		e.count++
+		if e.Times != 0 && e.count >= e.Times {
+			h.unname(e)
+			h.unsave(e)
+			heap.Remove(&h.entries, i)
+			h.listener.OnRemove(*e)
+			return
+		}
+		h.save(e)
+	})
+	return err
+}
+
+// Pause an entry with entry-id, it stays in the Cron but does not run.
+func (h *Heap) Pause(id int) {
+	h.do(func() {
+		i, e := h.findEntry(id)
+		if e == nil || e.retryOf != 0 || e.Paused {
+			return
+		}
+		e.Paused = true
+		e.Next = time.Time{}
+		heap.Fix(&h.entries, i)
+		h.save(e)
+	})
+}
+
+// Resume a paused entry with entry-id, the next time is calculated from now.
+func (h *Heap) Resume(id int) {
+	h.do(func() {
+		i, e := h.findEntry(id)
+		if e == nil || e.retryOf != 0 || !e.Paused {
+			return
+		}
+		e.Paused = false
+		if h.running {
+			e.Next = e.Schedule.Next(h.now())
+			heap.Fix(&h.entries, i)
+		}
+		h.save(e)
+	})
+}
+
+// RemoveWhere removes the entries matched by sel, and returns their entry-ids.
+//
+// The retries of entries are not matched, they are removed with the entries.
+func (h *Heap) RemoveWhere(sel Selector) []int {
+	var ids []int
+	h.do(func() {
+		for _, e := range h.entries {
+			if e.retryOf == 0 && sel(*e) {
+				ids = append(ids, e.ID)
+			}
+		}
+		for _, id := range ids {
+			h.removeEntry(id)
+		}
+	})
+	return ids
+}
+
+// PauseWhere pauses the entries matched by sel, and returns their entry-ids.
+//
+// The entries already paused and the retries of entries are not matched.
+func (h *Heap) PauseWhere(sel Selector) []int {
+	var ids []int
+	h.do(func() {
+		for _, e := range h.entries {
+			if e.retryOf != 0 || e.Paused || !sel(*e) {
+				continue
+			}
+			e.Paused = true
+			e.Next = time.Time{}
+			h.save(e)
+			ids = append(ids, e.ID)
+		}
+		if len(ids) > 0 {
+			heap.Init(&h.entries)
+		}
+	})
+	return ids
+}
+
+// ResumeWhere resumes the paused entries matched by sel, and returns their entry-ids.
+func (h *Heap) ResumeWhere(sel Selector) []int {
+	var ids []int
+	h.do(func() {
+		now := h.now()
+		for _, e := range h.entries {
+			if e.retryOf != 0 || !e.Paused || !sel(*e) {
+				continue
+			}
+			e.Paused = false
+			if h.running {
+				e.Next = e.Schedule.Next(now)
+			}
+			h.save(e)
+			ids = append(ids, e.ID)
+		}
+		if len(ids) > 0 {
+			heap.Init(&h.entries)
+		}
+	})
+	return ids
+}
+
+// Entries returns the snapshots of all entries, ordered by the next time.
+//
+// The retries of entries are listed too, see Entry.RetryOf.
+func (h *Heap) Entries() []Entry {
+	return h.entriesWhere(func(Entry) bool { return true })
+}
+
+// EntriesWhere returns the snapshots of the entries matched by sel,
+// ordered by the next time.
+//
+// The retries of entries are not matched.
+func (h *Heap) EntriesWhere(sel Selector) []Entry {
+	return h.entriesWhere(func(e Entry) bool { return e.retryOf == 0 && sel(e) })
+}
+
+// entriesWhere returns the snapshots of the entries and retries matched by sel,
+// ordered by the next time.
+func (h *Heap) entriesWhere(sel Selector) []Entry {
+	var result []Entry
+	h.do(func() {
+		result = make([]Entry, 0, len(h.entries))
+		for _, e := range h.entries {
+			if sel(*e) {
+				result = append(result, *e)
+			}
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Next, result[j].Next
+		switch {
+		case a.Equal(b):
+			return result[i].ID < result[j].ID
+		// zero is greater than any other time
+		case a.IsZero():
+			return false
+		case b.IsZero():
+			return true
+		}
+		return a.Before(b)
+	})
+	return result
+}
+
+// Entry returns the snapshot of an entry with entry-id.
+func (h *Heap) Entry(id int) (Entry, bool) {
+	var (
+		result Entry
+		ok     bool
+	)
+	h.do(func() {
+		for _, e := range h.entries {
+			if e.ID == id {
+				result, ok = *e, true
+				return
+			}
+		}
+	})
+	return result, ok
+}
+
+// EntryByName returns the snapshot of an entry with the name.
+func (h *Heap) EntryByName(name string) (Entry, bool) {
+	var (
+		result Entry
+		ok     bool
+	)
+	h.do(func() {
+		var id int
+		if id, ok = h.names[name]; ok {
+			_, e := h.findEntry(id)
+			result = *e
+		}
+	})
+	return result, ok
+}
+
+// Restore the entries saved in the Store, see WithStore.
+//
+// The job of entry is created by the factory of its job type in the Registry.
+// If an entry with the same name is added, its counts, times and paused
+// state are restored. The entry keeps its id if it is not used.
+//
+// It should be called before Run, so that the entries due while the process
+// is down misfire, see MisfirePolicy. It restores the other entries if an
+// entry fails, and returns the first error.
+func (h *Heap) Restore() error {
+	if h.store == nil {
+		return ErrNoStore
+	}
+	records, err := h.store.Load()
+	if err != nil {
+		return err
+	}
+	var first error
+	for _, r := range records {
+		if err := h.restore(r); err != nil {
+			h.logger.Error("Restore entry failure, name: %s: %s", r.Name, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	h.do(func() {
+		h.synced = true
+		for _, e := range h.entries {
+			h.save(e)
+		}
+	})
+	return first
+}
+
+// restore restores the entry of the record.
+func (h *Heap) restore(r EntryRecord) error {
+	var (
+		reg registered
+		ok  bool
+	)
+	if h.registry != nil {
+		reg, ok = h.registry.lookup(r.JobType)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJobType, r.JobType)
+	}
+	schedule, err := h.parser.Parse(r.Spec)
+	if err != nil {
+		return err
+	}
+	job := reg.factory()
+	if err := checkJob(job); err != nil {
+		return err
+	}
+	entry := &Entry{
+		Spec:     r.Spec,
+		Schedule: schedule,
+		Job:      job,
+		state:    newEntryState(),
+	}
+	for _, opt := range reg.opts {
+		opt(entry)
+	}
+	entry.Name = r.Name
+	entry.JobType = r.JobType
+	entry.Labels = r.Labels
+	entry.Times = r.Times
+	h.do(func() {
+		if id, ok := h.names[r.Name]; ok {
+			i, e := h.findEntry(id)
+			e.restore(r, h.running)
+			if h.running && !e.Paused && e.Next.IsZero() {
+				e.Next = e.Schedule.Next(h.now())
+			}
+			heap.Fix(&h.entries, i)
+			return
+		}
+		entry.ID = r.ID
+		if _, e := h.findEntry(r.ID); e != nil || r.ID <= 0 {
+			entry.ID = h.lastID + 1
+		}
+		if entry.ID > h.lastID {
+			h.lastID = entry.ID
+		}
+		entry.restore(r, h.running)
+		entry.wrappedJob = h.wrap(entry)
+		h.pushEntry(entry)
+	})
+	return nil
+}
+
 // Run the Cron in synchronous mode, or no-op if alreay running.
 func (h *Heap) Run() {
 	h.lock.Lock()
@@ -99,6 +579,8 @@ func (h *Heap) Run() {
 		return
 	}
 	h.running = true
+	h.synced = true
+	h.ctx, h.cancel = context.WithCancel(context.Background())
 	h.lock.Unlock()
 	h.logger.Info("Start cron")
 	h.run()
@@ -106,15 +588,41 @@ func (h *Heap) Run() {
 
 func (h *Heap) run() {
 	// Init all schedule
-	now := time.Now()
+	now := h.now()
+	n := 0
 	for _, e := range h.entries {
-		e.Next = e.Schedule.Next(now)
+		e.state.reset(h.ctx)
+		if e.Paused {
+			e.Next = time.Time{}
+			h.entries[n] = e
+			n++
+			continue
+		}
+		// the entry due while the Cron is stopped misfires in the loop,
+		// unless it catches up
+		if h.catchUp(e, now) || e.Next.IsZero() || e.Next.After(now) {
+			e.Next = e.Schedule.Next(now)
+		}
 		if e.RunFirst {
-			go e.Job.Run()
 			e.RunFirst = false
-			e.count++
+			if h.startJob(e, now) {
+				e.count++
+			}
+		}
+		if e.Times != 0 && e.count >= e.Times {
+			h.unname(e)
+			h.unsave(e)
+			h.listener.OnRemove(*e)
+			continue
 		}
+		h.save(e)
+		h.entries[n] = e
+		n++
+	}
+	for i := n; i < len(h.entries); i++ {
+		h.entries[i] = nil
 	}
+	h.entries = h.entries[:n]
 	// Init min-heap
 	heap.Init(&h.entries)
 
@@ -140,7 +648,7 @@ func (h *Heap) run() {
 			// Waiting along time util a new job join in.
 			timer.Reset(defaultWaitTime)
 		} else {
-			timer.Reset(h.entries[0].Next.Sub(time.Now()))
+			timer.Reset(h.entries[0].Next.Sub(h.now()))
 		}
 
 		for {
@@ -149,7 +657,7 @@ func (h *Heap) run() {
 				if len(h.entries) == 0 {
 					break
 				}
-				now = time.Now()
+				now = h.now()
 				// run job from heap
 				for len(h.entries) > 0 {
 					entry := h.entries[0]
@@ -157,21 +665,31 @@ func (h *Heap) run() {
 						break
 					}
 					entry = heap.Pop(&h.entries).(*Entry)
-					go entry.Job.Run()
-					entry.count++
-					if entry.Times != 0 && entry.count >= entry.Times {
+					if entry.retryOf != 0 {
+						h.startRetry(entry)
+						continue
+					}
+					if entry.Retry.enabled() {
+						// the regular run replaces the pending retries
+						h.removeRetries(entry.ID)
+					}
+					if !h.fire(entry, now) {
+						h.unname(entry)
+						h.unsave(entry)
+						h.listener.OnRemove(*entry)
 						continue
 					}
 					entry.Prev = entry.Next
 					entry.Next = entry.Schedule.Next(now)
 					heap.Push(&h.entries, entry)
+					h.save(entry)
 				}
 			case entry := <-h.add:
-				now = time.Now()
-				entry.Next = entry.Schedule.Next(now)
-				heap.Push(&h.entries, entry)
+				h.pushEntry(entry)
 			case id := <-h.remove:
 				h.removeEntry(id)
+			case fn := <-h.exec:
+				fn()
 			case <-h.stop:
 				return
 			case <-h.release:
@@ -189,7 +707,27 @@ func (h *Heap) Stop() {
 	if h.running {
 		h.stop <- struct{}{}
 		h.running = false
+		h.cancel()
+		h.removeRetries(0)
+		h.flush()
 		h.logger.Info("Stop cron")
+		h.listener.OnStop()
+	}
+}
+
+// Shutdown stops the Cron, and waits for the running jobs to finish.
+//
+// If ctx is done before that, it returns the entry-ids of the jobs still
+// running, and the error of ctx.
+func (h *Heap) Shutdown(ctx context.Context) ([]int, error) {
+	h.Stop()
+	select {
+	case <-h.jobs.wait():
+		// the finished jobs changed the time of the last success
+		h.flush()
+		return nil, nil
+	case <-ctx.Done():
+		return h.jobs.ids(), ctx.Err()
 	}
 }
 
@@ -199,19 +737,422 @@ func (h *Heap) Release() {
 	defer h.lock.Unlock()
 	if h.running {
 		h.release <- struct{}{}
+		h.running = false
+		h.cancel()
+		h.listener.OnStop()
+	}
+	for _, e := range h.entries {
+		if e.retryOf != 0 {
+			continue
+		}
+		e.state.close()
+		h.listener.OnRemove(*e)
 	}
 	h.entries = h.entries[:0]
+	h.names = make(map[string]int)
+	h.flush()
 	h.logger.Info("Release cron")
 }
 
+// checkJob returns ErrInvalidJob if job is nil.
+func checkJob(job Job) error {
+	if job == nil {
+		return ErrInvalidJob
+	}
+	return nil
+}
+
+// wrap returns the job of entry decorated by all wrappers.
+func (h *Heap) wrap(e *Entry) Job {
+	return h.chain.Then(NewChain(e.wrappers...).Then(e.Job))
+}
+
+// startJob runs the job of entry scheduled at the given time in a new goroutine,
+// and reports whether the job is started according to the overlap policy.
+//
+// The job of a retry is reported with the entry-id of the failed entry.
+func (h *Heap) startJob(e *Entry, scheduled time.Time) bool {
+	entry := *e
+	if e.retryOf != 0 {
+		entry.ID = e.retryOf
+	}
+	if running := atomic.LoadInt32(&e.state.running); running > 0 {
+		switch {
+		case e.Overlap == OverlapSkip:
+			e.skipped++
+			h.logger.Info("Skip job, id: %d, spec: %s, the previous one is still running", entry.ID, e.Spec)
+			h.listener.OnSkip(entry, scheduled)
+			return false
+		case e.Overlap == OverlapQueue && running > 1:
+			// at most one job is queued, so that a slow job does not pile up
+			e.skipped++
+			h.logger.Info("Skip job, id: %d, spec: %s, a queued one is still pending", entry.ID, e.Spec)
+			h.listener.OnSkip(entry, scheduled)
+			return false
+		case e.Overlap == OverlapQueue:
+			e.queued++
+			h.logger.Info("Queue job, id: %d, spec: %s, the previous one is still running", entry.ID, e.Spec)
+		}
+	}
+	atomic.AddInt32(&e.state.running, 1)
+	h.jobs.start(entry.ID)
+	go h.runJob(entry, scheduled)
+	return true
+}
+
+// runJob runs the job of entry, records it in the history,
+// and recovers the panic of it.
+func (h *Heap) runJob(entry Entry, scheduled time.Time) {
+	defer h.jobs.done(entry.ID)
+	defer atomic.AddInt32(&entry.state.running, -1)
+	if !h.lockFiring(entry, scheduled) {
+		return
+	}
+	if entry.Overlap == OverlapQueue {
+		entry.state.serial <- struct{}{}
+		defer func() { <-entry.state.serial }()
+	}
+	record := entry.state.begin(scheduled, h.now())
+	h.listener.OnJobStart(entry, scheduled)
+	if entry.Timeout > 0 {
+		timer := time.AfterFunc(entry.Timeout, func() {
+			h.logger.Error("Job timeout, id: %d, spec: %s, timeout: %s", entry.ID, entry.Spec, entry.Timeout)
+			entry.state.update(record, func(r *RunRecord) { r.TimedOut = true })
+		})
+		defer timer.Stop()
+	}
+	var err error
+	defer func() {
+		r := recover()
+		result := entry.state.end(record, h.now(), err, r)
+		if result.Err != nil {
+			h.logger.Error("Job error, id: %d, spec: %s: %s", entry.ID, entry.Spec, result.Err)
+		}
+		if (result.Err != nil || r != nil) && entry.Retry.enabled() {
+			h.retry(entry)
+		}
+		if h.writer != nil && entry.JobType != "" {
+			// the time of the last success is changed
+			h.writer.touch(entry.Name)
+		}
+		if r != nil {
+			h.logger.Error("Job panic, id: %d, spec: %s: %v\n%s", entry.ID, entry.Spec, r, debug.Stack())
+			if h.panicHandler != nil {
+				h.panicHandler(entry, r)
+			}
+		}
+		h.listener.OnJobEnd(entry, result)
+	}()
+	if j, ok := entry.wrappedJob.(contextRunner); ok {
+		ctx := context.WithValue(entry.state.context(), entryIDKey{}, entry.ID)
+		ctx = context.WithValue(ctx, scheduledKey{}, scheduled)
+		if entry.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, entry.Timeout)
+			defer cancel()
+		}
+		// the context of the run is passed through the wrappers to the job
+		call := &runCall{ctx: ctx}
+		j.contextJob().enter(call)
+		defer func() { err = j.contextJob().leave(call) }()
+	}
+	entry.wrappedJob.Run()
+}
+
+// lockFiring reports whether the firing of entry scheduled at the given time
+// is locked by the Cron among replicas. The entry without a name and the
+// retries are not locked, the retries run in the replica of the failed job.
+func (h *Heap) lockFiring(e Entry, scheduled time.Time) bool {
+	if h.distLocker == nil || e.Name == "" || e.retryOf != 0 {
+		return true
+	}
+	ok, err := h.distLocker.TryLock(lockKey(e.Name, scheduled), h.lockTTL)
+	if err != nil {
+		h.logger.Error("Lock job failure, id: %d, spec: %s: %s", e.ID, e.Spec, err)
+		return false
+	}
+	if !ok {
+		h.logger.Debug("Job is locked by another replica, id: %d, spec: %s, scheduled: %s", e.ID, e.Spec, scheduled)
+	}
+	return ok
+}
+
+// do calls fn with the exclusive access to the entries,
+// in the goroutine of run if the Cron is running.
+func (h *Heap) do(fn func()) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.doLocked(fn)
+}
+
+// doLocked is like do, but h.lock is held by the caller.
+func (h *Heap) doLocked(fn func()) {
+	if !h.running {
+		fn()
+		return
+	}
+	done := make(chan struct{})
+	h.exec <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
+
+// now returns the current time in the location of the Cron.
+func (h *Heap) now() time.Time {
+	return time.Now().In(h.location)
+}
+
+// pushEntry pushes a new entry into the entries,
+// and calculates its next time if the Cron is running.
+//
+// The entry is dropped if it reaches the max execute times by catching up.
+func (h *Heap) pushEntry(e *Entry) {
+	if h.running {
+		now := h.now()
+		e.state.reset(h.ctx)
+		if !e.Paused {
+			h.catchUp(e, now)
+			e.Next = e.Schedule.Next(now)
+		}
+		if e.Times != 0 && e.count >= e.Times {
+			h.unsave(e)
+			h.listener.OnAdd(*e)
+			h.listener.OnRemove(*e)
+			return
+		}
+	}
+	if e.Name != "" {
+		h.names[e.Name] = e.ID
+	}
+	heap.Push(&h.entries, e)
+	h.save(e)
+	h.listener.OnAdd(*e)
+}
+
 func (h *Heap) removeEntry(id int) {
+	i, e := h.findEntry(id)
+	if e == nil {
+		return
+	}
+	heap.Remove(&h.entries, i)
+	if e.retryOf != 0 {
+		return
+	}
+	e.state.close()
+	h.unname(e)
+	h.unsave(e)
+	h.removeRetries(e.ID)
+	h.listener.OnRemove(*e)
+}
+
+// save saves the entry in the store in background, if it has a name and a job type.
+//
+// The entries are not saved before Restore or Run,
+// so that they do not overwrite the saved ones.
+func (h *Heap) save(e *Entry) {
+	if !h.synced || h.writer == nil || e.Name == "" || e.JobType == "" || e.retryOf != 0 {
+		return
+	}
+	h.writer.save(e)
+}
+
+// unsave deletes the entry from the store in background.
+func (h *Heap) unsave(e *Entry) {
+	if !h.synced || h.writer == nil || e.Name == "" || e.JobType == "" || e.retryOf != 0 {
+		return
+	}
+	h.writer.delete(e.Name)
+}
+
+// flush writes the changes of entries to the store now.
+func (h *Heap) flush() {
+	if h.writer != nil {
+		h.writer.flush()
+	}
+}
+
+// retry schedules the retry of the failed job of entry,
+// which is the entry or a retry of it.
+func (h *Heap) retry(e Entry) {
+	id := e.ID
+	if e.retryOf != 0 {
+		id = e.retryOf
+	}
+	attempt := e.Attempt() + 1
+	if attempt > e.Retry.MaxAttempts {
+		h.logger.Error("Job failed after %d attempts, id: %d, spec: %s", e.Retry.MaxAttempts, id, e.Spec)
+		return
+	}
+	delay := e.Retry.Backoff(attempt - 1)
+	h.do(func() {
+		if !h.running {
+			return
+		}
+		_, parent := h.findEntry(id)
+		if parent == nil || parent.Paused {
+			return
+		}
+		next := h.now().Add(delay)
+		if !parent.Next.IsZero() && !next.Before(parent.Next) {
+			h.logger.Info("Skip retry job, id: %d, spec: %s, attempt: %d, the next run is at %s", id, parent.Spec, attempt, parent.Next)
+			return
+		}
+		h.lastID++
+		retry := &Entry{
+			ID:       h.lastID,
+			Name:     parent.Name,
+			Labels:   parent.Labels,
+			Spec:     parent.Spec,
+			JobType:  parent.JobType,
+			Job:      parent.Job,
+			Schedule: onceSchedule(next),
+			Next:     next,
+			retryOf:  id,
+			attempt:  attempt,
+			state:    parent.state,
+		}
+		heap.Push(&h.entries, retry)
+		h.logger.Info("Retry job, id: %d, spec: %s, attempt: %d, at: %s", id, parent.Spec, attempt, next)
+	})
+}
+
+// startRetry starts the retry popped from the entries with the job of
+// the failed entry, it is dropped if the entry is removed or paused.
+func (h *Heap) startRetry(r *Entry) {
+	_, parent := h.findEntry(r.retryOf)
+	if parent == nil || parent.Paused {
+		return
+	}
+	r.Job = parent.Job
+	r.Overlap = parent.Overlap
+	r.Timeout = parent.Timeout
+	r.Retry = parent.Retry
+	r.wrappedJob = parent.wrappedJob
+	h.startJob(r, r.Next)
+}
+
+// removeRetries removes the retries of the entry with entry-id,
+// or all retries if id is 0.
+func (h *Heap) removeRetries(id int) {
+	n := 0
+	for _, e := range h.entries {
+		if e.retryOf != 0 && (id == 0 || e.retryOf == id) {
+			continue
+		}
+		h.entries[n] = e
+		n++
+	}
+	if n == len(h.entries) {
+		return
+	}
+	for i := n; i < len(h.entries); i++ {
+		h.entries[i] = nil
+	}
+	h.entries = h.entries[:n]
+	heap.Init(&h.entries)
+}
+
+// fire starts the job of entry due at now according to its misfire policy,
+// and reports the missed times. It returns whether the entry stays in Cron,
+// it does not if the entry reaches the max execute times.
+func (h *Heap) fire(e *Entry, now time.Time) bool {
+	due := append([]time.Time{e.Next}, missed(e.Schedule, e.Next, now, maxMisfires)...)
+	run, dropped := due[:1], due[1:]
+	if now.Sub(e.Next) > e.misfireThreshold() {
+		switch e.Misfire {
+		case MisfireFireAll:
+			if len(due) > maxMisfires {
+				run, dropped = due[:maxMisfires], due[maxMisfires:]
+			} else {
+				run, dropped = due, nil
+			}
+		case MisfireSkip:
+			run, dropped = nil, due
+		}
+	}
+	for _, t := range run {
+		if h.startJob(e, t) {
+			e.count++
+		}
+		if e.Times != 0 && e.count >= e.Times {
+			return false
+		}
+	}
+	if len(dropped) > 0 {
+		h.logger.Info("Misfire job, id: %d, spec: %s, missed: %d", e.ID, e.Spec, len(dropped))
+		for _, t := range dropped {
+			h.listener.OnMisfire(*e, t)
+		}
+	}
+	return true
+}
+
+// catchUp runs the job of entry for the times missed since its last success,
+// at most the CatchUp times of entry, and reports whether any time is missed.
+// It runs once for an entry, and stops if the entry reaches the max execute times.
+func (h *Heap) catchUp(e *Entry, now time.Time) bool {
+	if e.CatchUp <= 0 {
+		return false
+	}
+	n := e.CatchUp
+	e.CatchUp = 0
+	last := e.LastSuccess()
+	if last.IsZero() {
+		last = e.Prev
+	}
+	if last.IsZero() {
+		return false
+	}
+	times := missed(e.Schedule, last, now, maxMisfires)
+	if len(times) == 0 {
+		return false
+	}
+	run, dropped := times, []time.Time(nil)
+	if len(times) > n {
+		run, dropped = times[:n], times[n:]
+	}
+	h.logger.Info("Catch up job, id: %d, spec: %s, missed: %d, run: %d", e.ID, e.Spec, len(times), len(run))
+	for _, t := range run {
+		if h.startJob(e, t) {
+			e.count++
+		}
+		e.Prev = t
+		if e.Times != 0 && e.count >= e.Times {
+			return true
+		}
+	}
+	for _, t := range dropped {
+		h.listener.OnMisfire(*e, t)
+	}
+	return true
+}
+
+// missed returns the times of schedule after from until now, at most max.
+func missed(s Schedule, from, now time.Time, max int) []time.Time {
+	var times []time.Time
+	for t := s.Next(from); !t.IsZero() && !t.After(now) && len(times) < max; t = s.Next(t) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// unname releases the name of entry.
+func (h *Heap) unname(e *Entry) {
+	if e.Name != "" && h.names[e.Name] == e.ID {
+		delete(h.names, e.Name)
+	}
+}
+
+// findEntry returns the index and the entry with entry-id, or nil if not found.
+func (h *Heap) findEntry(id int) (int, *Entry) {
 	for i, e := range h.entries {
 		if e.ID == id {
-			heap.Remove(&h.entries, i)
-			// 循环里，只能删除一次，删除多了会导致 panic，命中了就删
-			return
+			return i, e
 		}
 	}
+	return -1, nil
 }
 
 // entries implement container/heap
@@ -1 +1,622 @@
 package cron
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location failure: %s", err)
+	}
+	datas := []struct {
+		opts   []Option
+		result *time.Location
+	}{
+		{
+			opts:   nil,
+			result: time.Local,
+		},
+		{
+			opts:   []Option{WithLocation(loc)},
+			result: loc,
+		},
+		{
+			opts:   []Option{WithLocation(nil)},
+			result: time.Local,
+		},
+		{
+			opts:   []Option{WithLocaltime(time.Now().In(loc))},
+			result: loc,
+		},
+	}
+	for _, data := range datas {
+		h := New(data.opts...).(*Heap)
+		if now := h.now(); now.Location() != data.result {
+			t.Fatalf("New(%v).now() in location %v, but got %v", data.opts, data.result, now.Location())
+		}
+	}
+
+	h := New(WithLocation(loc))
+	id := h.AddFunc("0 9 * * *", func() {})
+	go h.Run()
+	time.Sleep(10 * time.Millisecond)
+	e, _ := h.Entry(id)
+	h.Stop()
+	if _, offset := e.Next.Zone(); e.Next.Hour() != 9 || e.Next.Minute() != 0 || offset != 8*60*60 {
+		t.Fatalf("Next of 0 9 * * * with location %v => 09:00 +08:00, but got %v", loc, e.Next)
+	}
+}
+
+func TestAddWithInvalidLocation(t *testing.T) {
+	h := New(WithLogger(printfLogger(testPrintf{t})))
+	if id := h.AddFunc("CRON_TZ=Mars/Olympus 0 9 * * *", func() {}); id != 0 {
+		t.Fatalf("AddFunc with invalid time zone => 0, but got %d", id)
+	}
+	if id := h.AddFunc("CRON_TZ=Europe/Berlin 0 9 * * *", func() {}); id == 0 {
+		t.Fatalf("AddFunc with valid time zone => non-zero, but got 0")
+	}
+}
+
+func TestAddE(t *testing.T) {
+	h := New()
+	id, err := h.AddFuncE("0 24 * * *", func() {})
+	var pe *ParseError
+	if id != 0 || !errors.As(err, &pe) || pe.Field != Hour {
+		t.Fatalf("AddFuncE with invalid hour => (0, *ParseError), but got (%d, %v)", id, err)
+	}
+	id, err = h.AddFuncE("0 23 * * *", func() {})
+	if id == 0 || err != nil {
+		t.Fatalf("AddFuncE with valid spec => (non-zero, nil), but got (%d, %v)", id, err)
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	recovered := make(chan int, 1)
+	h := New(
+		WithLogger(printfLogger(testPrintf{t})),
+		WithPanicHandler(func(entry Entry, r interface{}) {
+			if r != "boom" {
+				t.Errorf("recovered => boom, but got %v", r)
+			}
+			recovered <- entry.ID
+		}),
+	)
+	id := h.AddFunc("@yearly", func() { panic("boom") }, WithEntryRunFirst())
+	go h.Run()
+	defer h.Stop()
+	select {
+	case got := <-recovered:
+		if got != id {
+			t.Fatalf("panic handler => entry %d, but got %d", id, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("panic handler is not called")
+	}
+}
+
+func TestWithChain(t *testing.T) {
+	var (
+		nums []string
+		done = make(chan struct{})
+	)
+	h := New(WithChain(appendingWrapper(&nums, "cron")))
+	h.AddFunc("@yearly", func() {
+		nums = append(nums, "job")
+		close(done)
+	}, WithEntryRunFirst(), WithEntryChain(appendingWrapper(&nums, "entry")))
+	go h.Run()
+	defer h.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("job is not called")
+	}
+	if result := strings.Join(nums, ","); result != "cron,entry,job" {
+		t.Fatalf("job with chains => cron,entry,job, but got %s", result)
+	}
+}
+
+func TestWithChainState(t *testing.T) {
+	var count int32
+	// once keeps its state for the job it decorates
+	once := func(j Job) Job {
+		var done int32
+		return FuncJob(func() {
+			if atomic.CompareAndSwapInt32(&done, 0, 1) {
+				j.Run()
+			}
+		})
+	}
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithChain(once))
+	h.Add("5ms", NewContextJob(ContextFuncJob(func(ctx context.Context) {
+		if _, ok := ScheduledFromContext(ctx); ok {
+			atomic.AddInt32(&count, 1)
+		}
+	})))
+	go h.Run()
+	time.Sleep(30 * time.Millisecond)
+	h.Shutdown(context.Background())
+	if count := atomic.LoadInt32(&count); count != 1 {
+		t.Fatalf("job with the wrapper running once => 1 time with context, but got %d", count)
+	}
+}
+
+func TestOverlapSkip(t *testing.T) {
+	var (
+		count   int32
+		release = make(chan struct{})
+	)
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t}))).(*Heap)
+	h.AddFunc("5ms", func() {
+		atomic.AddInt32(&count, 1)
+		<-release
+	}, WithEntryOverlap(OverlapSkip))
+	go h.Run()
+	time.Sleep(50 * time.Millisecond)
+	h.Stop()
+	close(release)
+	if count := atomic.LoadInt32(&count); count != 1 {
+		t.Fatalf("job with OverlapSkip runs => 1 times, but got %d", count)
+	}
+	if e := h.entries[0]; e.skipped == 0 || e.count != 1 {
+		t.Fatalf("entry with OverlapSkip => (skipped > 0, count 1), but got (%d, %d)", e.skipped, e.count)
+	}
+}
+
+func TestOverlapQueue(t *testing.T) {
+	var running, count int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t}))).(*Heap)
+	h.AddFunc("5ms", func() {
+		if atomic.AddInt32(&running, 1) > 1 {
+			t.Errorf("job with OverlapQueue runs concurrently")
+		}
+		time.Sleep(15 * time.Millisecond)
+		atomic.AddInt32(&count, 1)
+		atomic.AddInt32(&running, -1)
+	}, WithEntryOverlap(OverlapQueue), WithEntryMaxExecuteTimes(3))
+	go h.Run()
+	time.Sleep(100 * time.Millisecond)
+	h.Stop()
+	if count := atomic.LoadInt32(&count); count != 3 {
+		t.Fatalf("job with OverlapQueue runs => 3 times, but got %d", count)
+	}
+}
+
+func TestOverlapQueueBounded(t *testing.T) {
+	release := make(chan struct{})
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("5ms", func() { <-release }, WithEntryOverlap(OverlapQueue))
+	go h.Run()
+	time.Sleep(50 * time.Millisecond)
+	e, _ := h.Entry(id)
+	close(release)
+	h.Shutdown(context.Background())
+	// one job runs and one is queued, the other firings are skipped
+	if e.Queued() != 1 || e.Skipped() < 5 || len(e.History()) != 2 {
+		t.Fatalf("Entry(%d) of blocked job => (1 queued, >= 5 skipped, 2 runs), but got (%d, %d, %d)", id, e.Queued(), e.Skipped(), len(e.History()))
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	h := New(WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("@yearly", func() {
+		close(started)
+		<-release
+	}, WithEntryRunFirst())
+	h.AddFunc("@yearly", func() {})
+	go h.Run()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ids, err := h.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Shutdown with running job => ([%d], %v), but got (%v, %v)", id, context.DeadlineExceeded, ids, err)
+	}
+
+	close(release)
+	ids, err = h.Shutdown(context.Background())
+	if ids != nil || err != nil {
+		t.Fatalf("Shutdown without running job => (nil, nil), but got (%v, %v)", ids, err)
+	}
+}
+
+func TestEntryTimeout(t *testing.T) {
+	h := New(WithLogger(printfLogger(testPrintf{t}))).(*Heap)
+	h.AddFunc("@yearly", func() {
+		time.Sleep(30 * time.Millisecond)
+	}, WithEntryRunFirst(), WithEntryTimeout(10*time.Millisecond))
+	h.AddFunc("@yearly", func() {}, WithEntryRunFirst(), WithEntryTimeout(time.Second))
+	go h.Run()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failure: %s", err)
+	}
+	for _, e := range h.entries {
+		history := e.History()
+		if len(history) != 1 || history[0].End.IsZero() {
+			t.Fatalf("entry %d => 1 finished run, but got %+v", e.ID, history)
+		}
+		if timedOut := e.ID == 1; history[0].TimedOut != timedOut {
+			t.Fatalf("entry %d => timed out: %v, but got %v", e.ID, timedOut, history[0].TimedOut)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	h := New(WithParser(NewParser(ParseOptionAll)), WithLogger(printfLogger(testPrintf{t})))
+	yearly := h.AddFunc("@yearly", func() {}, WithEntryMaxExecuteTimes(3))
+	secondly := h.AddFunc("* * * * * *", func() {})
+	if entries := h.Entries(); len(entries) != 2 {
+		t.Fatalf("Entries before running => 2 entries, but got %d", len(entries))
+	}
+
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(10 * time.Millisecond)
+	entries := h.Entries()
+	if len(entries) != 2 || entries[0].ID != secondly || entries[1].ID != yearly {
+		t.Fatalf("Entries => [%d, %d], but got %+v", secondly, yearly, entries)
+	}
+	if !entries[0].Next.Before(entries[1].Next) {
+		t.Fatalf("Entries => ordered by next, but got %v, %v", entries[0].Next, entries[1].Next)
+	}
+
+	entry, ok := h.Entry(yearly)
+	if !ok || entry.ID != yearly || entry.Spec != "@yearly" || entry.Times != 3 || entry.Count() != 0 {
+		t.Fatalf("Entry(%d) => (@yearly, times 3, count 0), but got (%s, times %d, count %d)", yearly, entry.Spec, entry.Times, entry.Count())
+	}
+	h.Remove(yearly)
+	if _, ok := h.Entry(yearly); ok {
+		t.Fatalf("Entry(%d) after removed => false, but got true", yearly)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	var count int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("5ms", func() { atomic.AddInt32(&count, 1) })
+	h.Pause(id)
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(30 * time.Millisecond)
+	if count := atomic.LoadInt32(&count); count != 0 {
+		t.Fatalf("paused entry runs => 0 times, but got %d", count)
+	}
+	if e, _ := h.Entry(id); !e.Paused || !e.Next.IsZero() {
+		t.Fatalf("Entry(%d) => (paused, zero next), but got (%v, %v)", id, e.Paused, e.Next)
+	}
+
+	h.Resume(id)
+	time.Sleep(30 * time.Millisecond)
+	if count := atomic.LoadInt32(&count); count == 0 {
+		t.Fatalf("resumed entry runs => > 0 times, but got 0")
+	}
+
+	h.Pause(id)
+	paused := atomic.LoadInt32(&count)
+	time.Sleep(30 * time.Millisecond)
+	if count := atomic.LoadInt32(&count); count != paused {
+		t.Fatalf("paused entry runs => %d times, but got %d", paused, count)
+	}
+	if e, _ := h.Entry(id); !e.Paused || e.Count() != uint(paused) {
+		t.Fatalf("Entry(%d) => (paused, count %d), but got (%v, %d)", id, paused, e.Paused, e.Count())
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var old, count int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("1h", func() { atomic.AddInt32(&old, 1) })
+	go h.Run()
+	defer h.Stop()
+
+	if err := h.Update(id+1, "5ms"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Update(%d) => %v, but got %v", id+1, ErrEntryNotFound, err)
+	}
+	if err := h.Update(id, "5"); err == nil {
+		t.Fatalf("Update(%d) with invalid spec => error, but got nil", id)
+	}
+	if err := h.Update(id, "5ms", WithEntryJob(nil)); !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("Update(%d) with nil job => %v, but got %v", id, ErrInvalidJob, err)
+	}
+	err := h.Update(id, "5ms", WithEntryJob(FuncJob(func() { atomic.AddInt32(&count, 1) })), WithEntryMaxExecuteTimes(2))
+	if err != nil {
+		t.Fatalf("Update(%d) failure: %s", id, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if old, count := atomic.LoadInt32(&old), atomic.LoadInt32(&count); old != 0 || count != 2 {
+		t.Fatalf("updated entry => (old 0, new 2) times, but got (%d, %d)", old, count)
+	}
+	entries := h.Entries()
+	if len(entries) != 0 {
+		t.Fatalf("updated entry is removed after max execute times, but got %+v", entries)
+	}
+}
+
+// intervalSchedule is due every duration, it is much shorter than a second in tests.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// intervalParser parses the spec as the duration of intervalSchedule.
+type intervalParser struct{}
+
+func (intervalParser) Parse(spec string) (Schedule, error) {
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, err
+	}
+	return intervalSchedule(d), nil
+}
+
+// testPrintf redirect the output of logger to testing.T
+type testPrintf struct {
+	t *testing.T
+}
+
+func (p testPrintf) Printf(format string, params ...interface{}) {
+	p.t.Logf(format, params...)
+}
+
+func TestTrigger(t *testing.T) {
+	var count int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("1h", func() { atomic.AddInt32(&count, 1) })
+	counted := h.AddFunc("1h", func() {}, WithEntryCountTrigger(), WithEntryMaxExecuteTimes(2))
+	if err := h.Trigger(id); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Trigger(%d) before Run => %v, but got %v", id, ErrNotRunning, err)
+	}
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(5 * time.Millisecond)
+
+	if err := h.Trigger(counted + 1); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Trigger(%d) => %v, but got %v", counted+1, ErrEntryNotFound, err)
+	}
+	before, _ := h.Entry(id)
+	for i := 0; i < 2; i++ {
+		if err := h.Trigger(id); err != nil {
+			t.Fatalf("Trigger(%d) => nil, but got %v", id, err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if count := atomic.LoadInt32(&count); count != 2 {
+		t.Fatalf("triggered job runs => 2 times, but got %d", count)
+	}
+	e, _ := h.Entry(id)
+	if !e.Next.Equal(before.Next) || e.Count() != 0 || e.Triggered() != 2 {
+		t.Fatalf("Entry(%d) => (next %v, count 0, triggered 2), but got (%v, %d, %d)", id, before.Next, e.Next, e.Count(), e.Triggered())
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	running := h.AddFunc("1h", func() { <-release }, WithEntryOverlap(OverlapSkip))
+	h.Trigger(running)
+	time.Sleep(5 * time.Millisecond)
+	if err := h.Trigger(running); !errors.Is(err, ErrSkipped) {
+		t.Fatalf("Trigger(%d) while running => %v, but got %v", running, ErrSkipped, err)
+	}
+	if e, _ := h.Entry(running); e.Triggered() != 1 || e.Skipped() != 1 {
+		t.Fatalf("Entry(%d) => (triggered 1, skipped 1), but got (%d, %d)", running, e.Triggered(), e.Skipped())
+	}
+
+	h.Trigger(counted)
+	if e, ok := h.Entry(counted); !ok || e.Count() != 1 {
+		t.Fatalf("Entry(%d) => count 1, but got (%v, %d)", counted, ok, e.Count())
+	}
+	h.Trigger(counted)
+	if _, ok := h.Entry(counted); ok {
+		t.Fatalf("Entry(%d) reached max execute times => removed, but got it", counted)
+	}
+}
+
+func TestEntryName(t *testing.T) {
+	var old, count int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id, err := h.AddFuncE("1h", func() { atomic.AddInt32(&old, 1) }, WithEntryName("report"))
+	if err != nil {
+		t.Fatalf("AddFuncE(report) => nil, but got %v", err)
+	}
+	if _, err := h.AddFuncE("1h", func() {}, WithEntryName("report")); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("AddFuncE(report) again => %v, but got %v", ErrDuplicateName, err)
+	}
+	if _, err := h.AddOrReplace("", "1h", FuncJob(func() {})); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("AddOrReplace(\"\") => %v, but got %v", ErrInvalidName, err)
+	}
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(5 * time.Millisecond)
+
+	before, ok := h.EntryByName("report")
+	if !ok || before.ID != id {
+		t.Fatalf("EntryByName(report) => %d, but got (%v, %d)", id, ok, before.ID)
+	}
+	replaced, err := h.AddOrReplace("report", "1h", FuncJob(func() { atomic.AddInt32(&count, 1) }))
+	if err != nil || replaced != id {
+		t.Fatalf("AddOrReplace(report) => (%d, nil), but got (%d, %v)", id, replaced, err)
+	}
+	if e, _ := h.Entry(id); !e.Next.Equal(before.Next) {
+		t.Fatalf("AddOrReplace(report) with the same spec => next %v, but got %v", before.Next, e.Next)
+	}
+	h.AddOrReplace("report", "5ms", FuncJob(func() { atomic.AddInt32(&count, 1) }))
+	time.Sleep(30 * time.Millisecond)
+	if old, count := atomic.LoadInt32(&old), atomic.LoadInt32(&count); old != 0 || count == 0 {
+		t.Fatalf("replaced job runs => (0, > 0) times, but got (%d, %d)", old, count)
+	}
+	added, err := h.AddOrReplace("clean", "1h", FuncJob(func() {}))
+	if err != nil || added == id {
+		t.Fatalf("AddOrReplace(clean) => new id, but got (%d, %v)", added, err)
+	}
+	if err := h.Update(added, "1h", WithEntryName("report")); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("Update(%d) to report => %v, but got %v", added, ErrDuplicateName, err)
+	}
+
+	h.RemoveByName("report")
+	if _, ok := h.EntryByName("report"); ok {
+		t.Fatalf("EntryByName(report) after RemoveByName => not found, but got it")
+	}
+	if _, err := h.AddFuncE("1h", func() {}, WithEntryName("report")); err != nil {
+		t.Fatalf("AddFuncE(report) after RemoveByName => nil, but got %v", err)
+	}
+}
+
+func TestEntryLabels(t *testing.T) {
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	a1 := h.AddFunc("1h", func() {}, WithEntryLabels(map[string]string{"tenant": "a", "kind": "report"}))
+	a2 := h.AddFunc("1h", func() {}, WithEntryLabels(map[string]string{"tenant": "a"}))
+	b1 := h.AddFunc("1h", func() {}, WithEntryLabels(map[string]string{"tenant": "b"}))
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(5 * time.Millisecond)
+
+	ids := func(es []Entry) []int {
+		result := []int{}
+		for _, e := range es {
+			result = append(result, e.ID)
+		}
+		sort.Ints(result)
+		return result
+	}
+	datas := []struct {
+		labels map[string]string
+		ids    []int
+	}{
+		{map[string]string{"tenant": "a"}, []int{a1, a2}},
+		{map[string]string{"tenant": "a", "kind": "report"}, []int{a1}},
+		{map[string]string{"tenant": "c"}, []int{}},
+		{nil, []int{a1, a2, b1}},
+	}
+	for _, data := range datas {
+		if got := ids(h.EntriesWhere(MatchLabels(data.labels))); !reflect.DeepEqual(got, data.ids) {
+			t.Fatalf("EntriesWhere(%v) => %v, but got %v", data.labels, data.ids, got)
+		}
+	}
+
+	tenantA := MatchLabels(map[string]string{"tenant": "a"})
+	if got := h.PauseWhere(tenantA); len(got) != 2 {
+		t.Fatalf("PauseWhere(tenant=a) => 2 entries, but got %v", got)
+	}
+	for _, e := range h.EntriesWhere(tenantA) {
+		if !e.Paused || !e.Next.IsZero() {
+			t.Fatalf("Entry(%d) => (paused, zero next), but got (%v, %v)", e.ID, e.Paused, e.Next)
+		}
+	}
+	if got := h.PauseWhere(tenantA); len(got) != 0 {
+		t.Fatalf("PauseWhere(tenant=a) again => no entries, but got %v", got)
+	}
+	if got := h.ResumeWhere(tenantA); len(got) != 2 {
+		t.Fatalf("ResumeWhere(tenant=a) => 2 entries, but got %v", got)
+	}
+	if got := h.RemoveWhere(tenantA); len(got) != 2 {
+		t.Fatalf("RemoveWhere(tenant=a) => 2 entries, but got %v", got)
+	}
+	if got := ids(h.Entries()); !reflect.DeepEqual(got, []int{b1}) {
+		t.Fatalf("Entries() => %v, but got %v", []int{b1}, got)
+	}
+}
+
+func TestMisfire(t *testing.T) {
+	datas := []struct {
+		name      string
+		policy    MisfirePolicy
+		threshold time.Duration
+		min, max  int32
+	}{
+		{"fire once", MisfireFireOnce, time.Millisecond, 1, 1},
+		{"fire all", MisfireFireAll, time.Millisecond, 4, 6},
+		{"skip", MisfireSkip, time.Millisecond, 0, 0},
+		{"not misfire", MisfireSkip, time.Hour, 1, 1},
+	}
+	for _, data := range datas {
+		var count int32
+		h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+		h.AddFunc("20ms", func() { atomic.AddInt32(&count, 1) }, WithEntryMisfire(data.policy, data.threshold))
+		go h.Run()
+		time.Sleep(5 * time.Millisecond)
+		h.Stop()
+		// the job due in 15ms misfires with 4 or 5 times missed
+		time.Sleep(100 * time.Millisecond)
+		go h.Run()
+		time.Sleep(5 * time.Millisecond)
+		h.Stop()
+		if count := atomic.LoadInt32(&count); count < data.min || count > data.max {
+			t.Fatalf("%s => [%d, %d] times, but got %d", data.name, data.min, data.max, count)
+		}
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	datas := []struct {
+		name        string
+		catchUp     int
+		lastSuccess time.Duration
+		expected    int32
+	}{
+		{"no catch up", 0, 275 * time.Millisecond, 0},
+		{"never succeeds", 3, 0, 0},
+		{"catch up once", 1, 275 * time.Millisecond, 1},
+		{"catch up 3 times", 3, 275 * time.Millisecond, 3},
+		{"catch up all", 10, 275 * time.Millisecond, 5},
+	}
+	for _, data := range datas {
+		var count int32
+		opts := []EntryOption{WithEntryCatchUp(data.catchUp)}
+		if data.lastSuccess > 0 {
+			opts = append(opts, WithEntryLastSuccess(time.Now().Add(-data.lastSuccess)))
+		}
+		h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+		id := h.AddFunc("50ms", func() { atomic.AddInt32(&count, 1) }, opts...)
+		go h.Run()
+		time.Sleep(10 * time.Millisecond)
+		h.Shutdown(context.Background())
+		if count := atomic.LoadInt32(&count); count != data.expected {
+			t.Fatalf("%s => %d times, but got %d", data.name, data.expected, count)
+		}
+		if e, _ := h.Entry(id); e.Count() != uint(data.expected) || e.CatchUp != 0 {
+			t.Fatalf("%s => (count %d, no catch up), but got (%d, %d)", data.name, data.expected, e.Count(), e.CatchUp)
+		}
+	}
+}
+
+func TestCatchUpMaxExecuteTimes(t *testing.T) {
+	for _, running := range []bool{false, true} {
+		var count int32
+		h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+		add := func() int {
+			return h.AddFunc("50ms", func() { atomic.AddInt32(&count, 1) }, WithEntryMaxExecuteTimes(1),
+				WithEntryCatchUp(3), WithEntryLastSuccess(time.Now().Add(-275*time.Millisecond)))
+		}
+		var id int
+		if !running {
+			id = add()
+		}
+		go h.Run()
+		time.Sleep(5 * time.Millisecond)
+		if running {
+			id = add()
+		}
+		time.Sleep(100 * time.Millisecond)
+		h.Shutdown(context.Background())
+		if count := atomic.LoadInt32(&count); count != 1 {
+			t.Fatalf("catch up 3 times with max execute times 1 (added while running: %v) => 1 time, but got %d", running, count)
+		}
+		if _, ok := h.Entry(id); ok {
+			t.Fatalf("Entry(%d) reached max execute times (added while running: %v) => removed, but got it", id, running)
+		}
+	}
+}
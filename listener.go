@@ -0,0 +1,85 @@
+package cron
+
+import "time"
+
+// Listener observes the lifecycle of the Cron and its entries.
+//
+// The entry passed to the callbacks is a snapshot. OnJobStart and OnJobEnd
+// are called in the goroutine of the job, the others are called with the
+// exclusive access to the entries of Cron, so they should return quickly
+// and must not call the methods of Cron.
+type Listener interface {
+	// OnAdd is called after an entry is added.
+	OnAdd(entry Entry)
+	// OnRemove is called after an entry is removed, or it reaches the max execute times.
+	OnRemove(entry Entry)
+	// OnJobStart is called before the job scheduled at the given time runs.
+	OnJobStart(entry Entry, scheduled time.Time)
+	// OnJobEnd is called after the job finishes, with the record of the run.
+	OnJobEnd(entry Entry, record RunRecord)
+	// OnSkip is called if the job scheduled at the given time is skipped by OverlapSkip.
+	OnSkip(entry Entry, scheduled time.Time)
+	// OnMisfire is called if the job scheduled at the given time does not run,
+	// because the Cron is stopped or delayed, see MisfirePolicy.
+	OnMisfire(entry Entry, scheduled time.Time)
+	// OnStop is called after the Cron stops.
+	OnStop()
+}
+
+// NopListener implements Listener with no-op callbacks.
+//
+// Embed it to implement a part of Listener.
+type NopListener struct{}
+
+func (NopListener) OnAdd(entry Entry)                           {}
+func (NopListener) OnRemove(entry Entry)                        {}
+func (NopListener) OnJobStart(entry Entry, scheduled time.Time) {}
+func (NopListener) OnJobEnd(entry Entry, record RunRecord)      {}
+func (NopListener) OnSkip(entry Entry, scheduled time.Time)     {}
+func (NopListener) OnMisfire(entry Entry, scheduled time.Time)  {}
+func (NopListener) OnStop()                                     {}
+
+// multiListener calls the listeners in order.
+type multiListener []Listener
+
+func (m multiListener) OnAdd(entry Entry) {
+	for _, l := range m {
+		l.OnAdd(entry)
+	}
+}
+
+func (m multiListener) OnRemove(entry Entry) {
+	for _, l := range m {
+		l.OnRemove(entry)
+	}
+}
+
+func (m multiListener) OnJobStart(entry Entry, scheduled time.Time) {
+	for _, l := range m {
+		l.OnJobStart(entry, scheduled)
+	}
+}
+
+func (m multiListener) OnJobEnd(entry Entry, record RunRecord) {
+	for _, l := range m {
+		l.OnJobEnd(entry, record)
+	}
+}
+
+func (m multiListener) OnSkip(entry Entry, scheduled time.Time) {
+	for _, l := range m {
+		l.OnSkip(entry, scheduled)
+	}
+}
+
+func (m multiListener) OnMisfire(entry Entry, scheduled time.Time) {
+	for _, l := range m {
+		l.OnMisfire(entry, scheduled)
+	}
+}
+
+func (m multiListener) OnStop() {
+	for _, l := range m {
+		l.OnStop()
+	}
+}
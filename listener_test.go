@@ -0,0 +1,126 @@
+package cron
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingListener records the names of events with entry-id.
+type recordingListener struct {
+	mu     sync.Mutex
+	events []string
+	ends   []RunRecord
+}
+
+func (l *recordingListener) record(event string, id int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event+":"+strconv.Itoa(id))
+}
+
+func (l *recordingListener) OnAdd(entry Entry)    { l.record("add", entry.ID) }
+func (l *recordingListener) OnRemove(entry Entry) { l.record("remove", entry.ID) }
+func (l *recordingListener) OnJobStart(entry Entry, scheduled time.Time) {
+	l.record("start", entry.ID)
+}
+func (l *recordingListener) OnJobEnd(entry Entry, record RunRecord) {
+	l.record("end", entry.ID)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ends = append(l.ends, record)
+}
+func (l *recordingListener) OnSkip(entry Entry, scheduled time.Time) { l.record("skip", entry.ID) }
+func (l *recordingListener) OnMisfire(entry Entry, scheduled time.Time) {
+	l.record("misfire", entry.ID)
+}
+func (l *recordingListener) OnStop() { l.record("stop", 0) }
+
+func (l *recordingListener) Events() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+func TestListener(t *testing.T) {
+	l := &recordingListener{}
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithListener(l))
+	id := h.AddFunc("5ms", func() { panic("boom") }, WithEntryMaxExecuteTimes(1))
+	other := h.AddFunc("1h", func() {})
+	go h.Run()
+	time.Sleep(30 * time.Millisecond)
+	h.Remove(other)
+	h.Stop()
+
+	expected := []string{
+		"add:" + strconv.Itoa(id),
+		"add:" + strconv.Itoa(other),
+		"remove:" + strconv.Itoa(id),
+		"start:" + strconv.Itoa(id),
+		"end:" + strconv.Itoa(id),
+		"remove:" + strconv.Itoa(other),
+		"stop:0",
+	}
+	events := l.Events()
+	// the job runs concurrently with the removal of entry reached the max execute times
+	sort.Strings(events[2:5])
+	sort.Strings(expected[2:5])
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("events => %v, but got %v", expected, events)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.ends) != 1 || l.ends[0].Panic != "boom" || l.ends[0].Duration() < 0 {
+		t.Fatalf("OnJobEnd => record with panic boom, but got %+v", l.ends)
+	}
+}
+
+func TestListenerSkip(t *testing.T) {
+	l := &recordingListener{}
+	release := make(chan struct{})
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithListener(l))
+	id := h.AddFunc("5ms", func() { <-release }, WithEntryOverlap(OverlapSkip))
+	go h.Run()
+	time.Sleep(30 * time.Millisecond)
+	h.Stop()
+	close(release)
+
+	e, _ := h.Entry(id)
+	var skipped uint
+	for _, event := range l.Events() {
+		if event == "skip:"+strconv.Itoa(id) {
+			skipped++
+		}
+	}
+	if skipped == 0 || skipped != e.Skipped() {
+		t.Fatalf("OnSkip => %d times, but got %d", e.Skipped(), skipped)
+	}
+}
+
+func TestMissed(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	datas := []struct {
+		now      time.Duration
+		max      int
+		expected int
+	}{
+		{5 * time.Millisecond, 10, 0},
+		{10 * time.Millisecond, 10, 1},
+		{35 * time.Millisecond, 10, 3},
+		{time.Hour, 10, 10},
+	}
+	for _, data := range datas {
+		times := missed(intervalSchedule(10*time.Millisecond), from, from.Add(data.now), data.max)
+		if len(times) != data.expected {
+			t.Fatalf("missed(10ms, %s, %d) => %d times, but got %v", data.now, data.max, data.expected, times)
+		}
+		for i, tt := range times {
+			if expected := from.Add(time.Duration(i+1) * 10 * time.Millisecond); !tt.Equal(expected) {
+				t.Fatalf("missed(10ms, %s, %d)[%d] => %v, but got %v", data.now, data.max, i, expected, tt)
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package metrics collects the metrics of cron, and exposes them
+// in the Prometheus text exposition format.
+//
+// It does not depend on the Prometheus client library.
+//
+//	m := metrics.New()
+//	c := cron.New(cron.WithListener(m))
+//	http.Handle("/metrics", m)
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jummyliu/cron"
+)
+
+// DefaultBuckets are the default upper bounds of histograms in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Collector collects the metrics of the Cron as a cron.Listener,
+// and serves them as a http.Handler.
+//
+// It is safe for concurrent use by multiple goroutines.
+type Collector struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	started   counterVec
+	succeeded counterVec
+	failed    counterVec
+	panicked  counterVec
+	skipped   counterVec
+	timedOut  counterVec
+	misfired  counterVec
+	duration  histogramVec
+	lag       histogramVec
+	entries   float64
+	running   float64
+}
+
+// Option configures the Collector.
+type Option func(c *Collector)
+
+// WithNamespace set the prefix of metric names.
+//
+// Default is "cron".
+func WithNamespace(namespace string) Option {
+	return func(c *Collector) {
+		c.namespace = namespace
+	}
+}
+
+// WithBuckets set the upper bounds of histograms in seconds.
+//
+// Default is DefaultBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = append([]float64(nil), buckets...)
+		sort.Float64s(c.buckets)
+	}
+}
+
+// New returns a Collector.
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		namespace: "cron",
+		buckets:   DefaultBuckets,
+		started:   counterVec{},
+		succeeded: counterVec{},
+		failed:    counterVec{},
+		panicked:  counterVec{},
+		skipped:   counterVec{},
+		timedOut:  counterVec{},
+		misfired:  counterVec{},
+		duration:  histogramVec{},
+		lag:       histogramVec{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// OnAdd implements cron.Listener.
+func (c *Collector) OnAdd(entry cron.Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries++
+}
+
+// OnRemove implements cron.Listener.
+func (c *Collector) OnRemove(entry cron.Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries--
+}
+
+// OnJobStart implements cron.Listener.
+func (c *Collector) OnJobStart(entry cron.Entry, scheduled time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started[label(entry)]++
+	c.running++
+}
+
+// OnJobEnd implements cron.Listener.
+func (c *Collector) OnJobEnd(entry cron.Entry, record cron.RunRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := label(entry)
+	c.running--
+	switch {
+	case record.Panic != nil:
+		c.panicked[name]++
+	case record.Err != nil:
+		c.failed[name]++
+	default:
+		c.succeeded[name]++
+	}
+	if record.TimedOut {
+		c.timedOut[name]++
+	}
+	c.duration.observe(name, record.Duration().Seconds(), c.buckets)
+	c.lag.observe(name, record.Start.Sub(record.Scheduled).Seconds(), c.buckets)
+}
+
+// OnSkip implements cron.Listener.
+func (c *Collector) OnSkip(entry cron.Entry, scheduled time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipped[label(entry)]++
+}
+
+// OnMisfire implements cron.Listener.
+func (c *Collector) OnMisfire(entry cron.Entry, scheduled time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.misfired[label(entry)]++
+}
+
+// OnStop implements cron.Listener.
+func (c *Collector) OnStop() {}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var b strings.Builder
+	c.writeCounter(&b, "jobs_started_total", "The count of started jobs.", c.started)
+	c.writeCounter(&b, "jobs_succeeded_total", "The count of jobs finished without error or panic.", c.succeeded)
+	c.writeCounter(&b, "jobs_failed_total", "The count of jobs returned an error.", c.failed)
+	c.writeCounter(&b, "jobs_panicked_total", "The count of jobs finished with panic.", c.panicked)
+	c.writeCounter(&b, "jobs_skipped_total", "The count of jobs skipped because the previous one is still running.", c.skipped)
+	c.writeCounter(&b, "jobs_timed_out_total", "The count of jobs exceeded the timeout of entry.", c.timedOut)
+	c.writeCounter(&b, "jobs_misfired_total", "The count of missed scheduled times.", c.misfired)
+	c.writeHistogram(&b, "job_duration_seconds", "The duration of jobs.", c.duration)
+	c.writeHistogram(&b, "job_schedule_lag_seconds", "The start time of jobs minus the scheduled time.", c.lag)
+	c.writeGauge(&b, "entries", "The count of entries in the Cron.", c.entries)
+	c.writeGauge(&b, "jobs_running", "The count of running jobs.", c.running)
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (c *Collector) name(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + "_" + name
+}
+
+func (c *Collector) writeHeader(b *strings.Builder, name, help, typ string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, typ)
+}
+
+func (c *Collector) writeCounter(b *strings.Builder, name, help string, v counterVec) {
+	name = c.name(name)
+	c.writeHeader(b, name, help, "counter")
+	for _, entry := range v.keys() {
+		fmt.Fprintf(b, "%s{entry=%s} %s\n", name, quote(entry), formatFloat(v[entry]))
+	}
+}
+
+func (c *Collector) writeGauge(b *strings.Builder, name, help string, v float64) {
+	name = c.name(name)
+	c.writeHeader(b, name, help, "gauge")
+	fmt.Fprintf(b, "%s %s\n", name, formatFloat(v))
+}
+
+func (c *Collector) writeHistogram(b *strings.Builder, name, help string, v histogramVec) {
+	name = c.name(name)
+	c.writeHeader(b, name, help, "histogram")
+	for _, entry := range v.keys() {
+		h := v[entry]
+		for i, le := range h.bounds {
+			fmt.Fprintf(b, "%s_bucket{entry=%s,le=\"%s\"} %d\n", name, quote(entry), formatFloat(le), h.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{entry=%s,le=\"+Inf\"} %d\n", name, quote(entry), h.count)
+		fmt.Fprintf(b, "%s_sum{entry=%s} %s\n", name, quote(entry), formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count{entry=%s} %d\n", name, quote(entry), h.count)
+	}
+}
+
+// counterVec is the counters by the label of entry.
+type counterVec map[string]float64
+
+func (v counterVec) keys() []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// histogram counts the observations by cumulative buckets.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // the count of observations less than or equal to bounds[i]
+	count  uint64
+	sum    float64
+}
+
+// histogramVec is the histograms by the label of entry.
+type histogramVec map[string]*histogram
+
+func (v histogramVec) observe(key string, value float64, bounds []float64) {
+	h, ok := v[key]
+	if !ok {
+		h = &histogram{
+			bounds: bounds,
+			counts: make([]uint64, len(bounds)),
+		}
+		v[key] = h
+	}
+	for i, le := range h.bounds {
+		if value <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+func (v histogramVec) keys() []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// label returns the name of entry, or the entry-id if it has no name.
+func label(entry cron.Entry) string {
+	if entry.Name != "" {
+		return entry.Name
+	}
+	return strconv.Itoa(entry.ID)
+}
+
+// quote quotes the label value as the text exposition format.
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jummyliu/cron"
+)
+
+func TestCollector(t *testing.T) {
+	c := New(WithBuckets([]float64{1, 0.1}))
+	report := cron.Entry{ID: 1, Name: "report"}
+	clean := cron.Entry{ID: 2}
+	scheduled := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	c.OnAdd(report)
+	c.OnAdd(clean)
+	c.OnJobStart(report, scheduled)
+	c.OnJobEnd(report, cron.RunRecord{
+		Scheduled: scheduled,
+		Start:     scheduled.Add(50 * time.Millisecond),
+		End:       scheduled.Add(2 * time.Second),
+		TimedOut:  true,
+	})
+	c.OnJobStart(clean, scheduled)
+	c.OnJobEnd(clean, cron.RunRecord{
+		Scheduled: scheduled,
+		Start:     scheduled,
+		End:       scheduled.Add(500 * time.Millisecond),
+		Panic:     "boom",
+	})
+	c.OnJobStart(clean, scheduled)
+	c.OnJobEnd(clean, cron.RunRecord{
+		Scheduled: scheduled,
+		Start:     scheduled,
+		End:       scheduled,
+		Err:       errors.New("failure"),
+	})
+	c.OnJobStart(clean, scheduled)
+	c.OnSkip(clean, scheduled)
+	c.OnMisfire(report, scheduled)
+	c.OnRemove(report)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type => text/plain; version=0.0.4, but got %s", ct)
+	}
+	body := rec.Body.String()
+	datas := []string{
+		"# TYPE cron_jobs_started_total counter\n",
+		`cron_jobs_started_total{entry="2"} 3` + "\n",
+		`cron_jobs_started_total{entry="report"} 1` + "\n",
+		`cron_jobs_succeeded_total{entry="report"} 1` + "\n",
+		`cron_jobs_failed_total{entry="2"} 1` + "\n",
+		`cron_jobs_panicked_total{entry="2"} 1` + "\n",
+		`cron_jobs_skipped_total{entry="2"} 1` + "\n",
+		`cron_jobs_timed_out_total{entry="report"} 1` + "\n",
+		`cron_jobs_misfired_total{entry="report"} 1` + "\n",
+		"# TYPE cron_job_duration_seconds histogram\n",
+		`cron_job_duration_seconds_bucket{entry="2",le="0.1"} 1` + "\n",
+		`cron_job_duration_seconds_bucket{entry="2",le="1"} 2` + "\n",
+		`cron_job_duration_seconds_bucket{entry="report",le="1"} 0` + "\n",
+		`cron_job_duration_seconds_bucket{entry="report",le="+Inf"} 1` + "\n",
+		`cron_job_duration_seconds_sum{entry="report"} 1.95` + "\n",
+		`cron_job_duration_seconds_count{entry="report"} 1` + "\n",
+		`cron_job_schedule_lag_seconds_bucket{entry="report",le="0.1"} 1` + "\n",
+		`cron_job_schedule_lag_seconds_sum{entry="report"} 0.05` + "\n",
+		"# TYPE cron_entries gauge\ncron_entries 1\n",
+		"# TYPE cron_jobs_running gauge\ncron_jobs_running 1\n",
+	}
+	for _, data := range datas {
+		if !strings.Contains(body, data) {
+			t.Fatalf("metrics => contains %q, but got\n%s", data, body)
+		}
+	}
+	if strings.Contains(body, `cron_jobs_succeeded_total{entry="2"}`) {
+		t.Fatalf("metrics => no succeeded job of entry 2, but got\n%s", body)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	datas := []struct {
+		s        string
+		expected string
+	}{
+		{"report", `"report"`},
+		{`a"b`, `"a\"b"`},
+		{`a\b`, `"a\\b"`},
+		{"a\nb", `"a\nb"`},
+	}
+	for _, data := range datas {
+		if got := quote(data.s); got != data.expected {
+			t.Fatalf("quote(%q) => %s, but got %s", data.s, data.expected, got)
+		}
+	}
+}
+
+func TestCollectorWithCron(t *testing.T) {
+	m := New(WithNamespace("app"))
+	c := cron.New(cron.WithListener(m))
+	// the entry runs first only, it is not due within the test
+	c.AddFunc("@yearly", func() {}, cron.WithEntryName("tick"), cron.WithEntryRunFirst())
+	go c.Run()
+	time.Sleep(50 * time.Millisecond)
+	c.Stop()
+
+	var b strings.Builder
+	m.WriteTo(&b)
+	for _, data := range []string{
+		`app_jobs_started_total{entry="tick"} 1` + "\n",
+		"app_entries 1\n",
+	} {
+		if !strings.Contains(b.String(), data) {
+			t.Fatalf("metrics => contains %q, but got\n%s", data, b.String())
+		}
+	}
+}
@@ -11,13 +11,76 @@ func WithParser(p Parser) Option {
 }
 
 func WithLogger(l Logger) Option {
-	return func (h *Heap) {
+	return func(h *Heap) {
 		h.logger = l
 	}
 }
 
+// WithLocation set the time zone in which the Cron evaluates schedules.
+//
+// Default is time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(h *Heap) {
+		if loc != nil {
+			h.location = loc
+		}
+	}
+}
+
+// WithLocaltime set the time zone of the Cron to the location of t.
+//
+// Deprecated: Use WithLocation instead.
 func WithLocaltime(t time.Time) Option {
-	return func (h *Heap) {
+	return WithLocation(t.Location())
+}
 
+// WithPanicHandler set the handler called after a job panics.
+//
+// The panic is always recovered and logged by the Cron, so that it does not
+// crash the process. The handler receives a snapshot of the entry.
+func WithPanicHandler(fn func(entry Entry, recovered interface{})) Option {
+	return func(h *Heap) {
+		h.panicHandler = fn
+	}
+}
+
+// WithChain decorates the jobs of all entries with wrappers.
+func WithChain(wrappers ...JobWrapper) Option {
+	return func(h *Heap) {
+		h.chain = NewChain(wrappers...)
+	}
+}
+
+// WithStore saves the entries with a name and a job type in store, and
+// reconstructs the jobs of them with the factories in registry on Cron.Restore.
+func WithStore(store Store, registry *Registry) Option {
+	return func(h *Heap) {
+		h.store = store
+		h.registry = registry
+	}
+}
+
+// WithDistributedLocker locks each firing of the entries with a name by l,
+// keyed by the name and the scheduled time, so that only one of the replicas
+// of Cron runs it. The job is not run if it fails to lock.
+//
+// The ttl should be longer than the clock skew among replicas, it is
+// DefaultLockTTL if it is not positive. The firings are only the same among
+// replicas if they are scheduled at fixed times, e.g. not by "@every" or
+// WithEntryRunFirst.
+func WithDistributedLocker(l DistributedLocker, ttl time.Duration) Option {
+	return func(h *Heap) {
+		if ttl <= 0 {
+			ttl = DefaultLockTTL
+		}
+		h.distLocker = l
+		h.lockTTL = ttl
+	}
+}
+
+// WithListener adds a listener to observe the lifecycle of the Cron and its entries.
+func WithListener(l Listener) Option {
+	return func(h *Heap) {
+		h.listener = append(h.listener, l)
 	}
 }
@@ -0,0 +1,71 @@
+package cron
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before the n-th retry of a failed job, n starts from 1.
+type Backoff func(n int) time.Duration
+
+// FixedBackoff returns a Backoff which always waits d.
+func FixedBackoff(d time.Duration) Backoff {
+	return func(n int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff returns a Backoff which waits base, 2*base, 4*base ...
+// up to max. The max is ignored if it is not positive.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n; i++ {
+			if d > (1<<63-1)/2 {
+				break
+			}
+			d *= 2
+			if max > 0 && d >= max {
+				break
+			}
+		}
+		if max > 0 && d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// JitterBackoff returns a Backoff which waits a random delay
+// between the half and the whole of the delay of b,
+// so that the retries of many entries are spread out.
+func JitterBackoff(b Backoff) Backoff {
+	return func(n int) time.Duration {
+		d := b(n)
+		if d <= 1 {
+			return d
+		}
+		half := d / 2
+		return half + time.Duration(rand.Int63n(int64(d-half)+1))
+	}
+}
+
+// RetryPolicy decides how to retry a failed job.
+//
+// The job fails if it panics, or the ErrJob returns an error.
+type RetryPolicy struct {
+	MaxAttempts int     // The max count of attempts, including the first one
+	Backoff     Backoff // The delay before each retry
+}
+
+// enabled reports whether the failed job is retried.
+func (p RetryPolicy) enabled() bool {
+	return p.MaxAttempts > 1 && p.Backoff != nil
+}
+
+// onceSchedule is the schedule of a retry, which runs once at the time.
+type onceSchedule time.Time
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	return time.Time(s)
+}
@@ -0,0 +1,170 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	datas := []struct {
+		name     string
+		backoff  Backoff
+		n        int
+		expected time.Duration
+	}{
+		{"fixed", FixedBackoff(time.Second), 1, time.Second},
+		{"fixed", FixedBackoff(time.Second), 5, time.Second},
+		{"exponential", ExponentialBackoff(time.Second, time.Minute), 1, time.Second},
+		{"exponential", ExponentialBackoff(time.Second, time.Minute), 3, 4 * time.Second},
+		{"exponential", ExponentialBackoff(time.Second, time.Minute), 7, time.Minute},
+		{"exponential", ExponentialBackoff(time.Second, 0), 11, 1024 * time.Second},
+	}
+	for _, data := range datas {
+		if d := data.backoff(data.n); d != data.expected {
+			t.Fatalf("%s(%d) => %s, but got %s", data.name, data.n, data.expected, d)
+		}
+	}
+	if d := ExponentialBackoff(time.Second, 0)(100); d <= 0 {
+		t.Fatalf("exponential(100) without max => > 0, but got %s", d)
+	}
+
+	jitter := JitterBackoff(FixedBackoff(time.Second))
+	for i := 0; i < 100; i++ {
+		if d := jitter(1); d < time.Second/2 || d > time.Second {
+			t.Fatalf("jitter(1) => [500ms, 1s], but got %s", d)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	failure := errors.New("failure")
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.Add("1h", NewErrJob(ErrFuncJob(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return failure
+	})), WithEntryRunFirst(), WithEntryRetry(3, FixedBackoff(20*time.Millisecond)))
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(10 * time.Millisecond)
+
+	var retry Entry
+	for _, e := range h.Entries() {
+		if e.RetryOf() == id {
+			retry = e
+		}
+	}
+	if retry.ID == 0 || retry.Attempt() != 2 {
+		t.Fatalf("Entries() => the retry of %d with attempt 2, but got %+v", id, retry)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Fatalf("failed job with 3 attempts runs => 3 times, but got %d", calls)
+	}
+	if entries := h.Entries(); len(entries) != 1 {
+		t.Fatalf("Entries() after the last attempt => 1 entry, but got %d", len(entries))
+	}
+	e, _ := h.Entry(id)
+	if e.Failures() != 3 || len(e.History()) != 3 || e.Count() != 1 {
+		t.Fatalf("Entry(%d) => (3 failures, 3 runs, count 1), but got (%d, %d, %d)", id, e.Failures(), len(e.History()), e.Count())
+	}
+}
+
+func TestRetryBeforeNext(t *testing.T) {
+	var calls int32
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("10ms", func() {
+		atomic.AddInt32(&calls, 1)
+		panic("boom")
+	}, WithEntryRetry(3, FixedBackoff(time.Hour)))
+	go h.Run()
+	time.Sleep(35 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	if entries := h.Entries(); len(entries) != 1 || entries[0].ID != id {
+		t.Fatalf("Entries() => only entry %d, but got %+v", id, entries)
+	}
+	e, _ := h.Entry(id)
+	if calls := atomic.LoadInt32(&calls); calls < 2 || uint(calls) != e.Count() {
+		t.Fatalf("job without retry runs => the %d regular runs, but got %d", e.Count(), calls)
+	}
+}
+
+func TestRemoveRetries(t *testing.T) {
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id := h.AddFunc("1h", func() { panic("boom") }, WithEntryRunFirst(), WithEntryRetry(3, FixedBackoff(time.Hour/2)))
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(10 * time.Millisecond)
+	if entries := h.Entries(); len(entries) != 2 {
+		t.Fatalf("Entries() => the entry and its retry, but got %+v", entries)
+	}
+	h.Remove(id)
+	if entries := h.Entries(); len(entries) != 0 {
+		t.Fatalf("Entries() after Remove(%d) => no entry, but got %+v", id, entries)
+	}
+}
+
+func TestRetryOfNamedEntry(t *testing.T) {
+	l := &recordingListener{}
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithListener(l))
+	id := h.AddFunc("1h", func() { panic("boom") }, WithEntryName("report"), WithEntryLabels(map[string]string{"team": "a"}),
+		WithEntryRunFirst(), WithEntryRetry(3, FixedBackoff(5*time.Millisecond)))
+	go h.Run()
+	time.Sleep(50 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	// the retries are reported as the failed entry
+	var starts int
+	for _, event := range l.Events() {
+		switch event {
+		case "start:" + strconv.Itoa(id):
+			starts++
+		case "end:" + strconv.Itoa(id), "add:" + strconv.Itoa(id), "stop:0":
+		default:
+			t.Fatalf("events of entry %d and its retries => only entry %d, but got %s", id, id, event)
+		}
+	}
+	if starts != 3 {
+		t.Fatalf("starts of entry %d with 3 attempts => 3, but got %d", id, starts)
+	}
+
+	// the retries are not matched by the selectors
+	h = New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})))
+	id = h.AddFunc("1h", func() { panic("boom") }, WithEntryName("report"), WithEntryLabels(map[string]string{"team": "a"}),
+		WithEntryRunFirst(), WithEntryRetry(3, FixedBackoff(time.Hour/2)))
+	go h.Run()
+	defer h.Stop()
+	time.Sleep(10 * time.Millisecond)
+	entries := h.Entries()
+	if len(entries) != 2 || entries[0].Name != "report" || entries[0].Labels["team"] != "a" {
+		t.Fatalf("Entries() => the retry of report with its labels first, but got %+v", entries)
+	}
+	retry := entries[0].ID
+	h.Pause(retry)
+	if e, _ := h.Entry(retry); e.Paused {
+		t.Fatalf("Pause(%d) of retry => not paused, but got paused", retry)
+	}
+	sel := MatchLabels(map[string]string{"team": "a"})
+	if entries := h.EntriesWhere(sel); len(entries) != 1 || entries[0].ID != id {
+		t.Fatalf("EntriesWhere(team=a) => only entry %d, but got %+v", id, entries)
+	}
+	for _, data := range []struct {
+		name string
+		fn   func(Selector) []int
+	}{
+		{"PauseWhere", h.PauseWhere},
+		{"ResumeWhere", h.ResumeWhere},
+		{"RemoveWhere", h.RemoveWhere},
+	} {
+		if ids := data.fn(sel); !reflect.DeepEqual(ids, []int{id}) {
+			t.Fatalf("%s(team=a) => [%d], but got %v", data.name, id, ids)
+		}
+	}
+}
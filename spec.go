@@ -1,11 +1,13 @@
 package cron
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type ParseOption int
@@ -42,6 +44,11 @@ const (
 	DescriptorEveryPrefix = "@every "
 )
 
+const (
+	TimeZonePrefix     = "TZ="
+	CronTimeZonePrefix = "CRON_TZ="
+)
+
 // places of all fields
 var places = []ParseOption{
 	Second,
@@ -62,6 +69,27 @@ var defaults = []string{
 	"*",
 }
 
+// String returns the name of a single field, e.g. "Minute".
+func (o ParseOption) String() string {
+	switch o {
+	case Second:
+		return "Second"
+	case Minute:
+		return "Minute"
+	case Hour:
+		return "Hour"
+	case DayOfMonth:
+		return "DayOfMonth"
+	case Month:
+		return "Month"
+	case DayOfWeek:
+		return "DayOfWeek"
+	case Descriptor:
+		return "Descriptor"
+	}
+	return fmt.Sprintf("ParseOption(%d)", int(o))
+}
+
 type Parser interface {
 	Parse(spec string) (Schedule, error)
 }
@@ -75,35 +103,67 @@ func NewParser(options ParseOption) Parser {
 }
 
 func (p *SpecParser) Parse(spec string) (Schedule, error) {
-	var err error
+	// wrap fill the spec and the column of the ParseError
+	wrap := func(err error, column int) error {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.Spec = spec
+			pe.Column += column
+		}
+		return err
+	}
+	// time zone
+	column := len(spec) - len(strings.TrimLeftFunc(spec, unicode.IsSpace))
+	trimmed := strings.TrimSpace(spec)
+	loc, expr, err := parseLocation(trimmed)
+	if err != nil {
+		return nil, wrap(err, column)
+	}
+	column += len(trimmed) - len(expr)
 	// descriptor
-	if strings.HasPrefix(spec, "@") {
+	if strings.HasPrefix(expr, "@") {
 		if p.options&Descriptor == 0 {
-			return nil, fmt.Errorf("Parser does not accept descriptor: %v", spec)
+			err := parseErrorf(KindDescriptor, "Parser does not accept descriptor: %v", expr)
+			err.Token = expr
+			return nil, wrap(err, column)
 		}
-		return parseDescriptor(spec)
+		schedule, err := parseDescriptor(expr)
+		if err != nil {
+			return nil, wrap(err, column)
+		}
+		if s, ok := schedule.(*SpecSchedule); ok {
+			s.Location = loc
+		}
+		return schedule, nil
 	}
 	// normalize
-	fields, err := normalizeFields(strings.Fields(spec), p.options)
+	fields, columns := fieldsIndex(expr)
+	fields, err = normalizeFields(fields, p.options)
 	if err != nil {
-		return nil, err
+		return nil, wrap(err, column)
 	}
+	columns = normalizeColumns(columns, p.options)
 
-	fieldWrap := func(field string, b bounds) uint64 {
+	fieldWrap := func(i int, place ParseOption, b bounds) uint64 {
 		if err != nil {
 			return 0
 		}
 		var bits uint64
-		bits, err = getField(field, b)
+		bits, err = getField(fields[i], b)
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.Field = place
+			err = wrap(err, column+columns[i])
+		}
 		return bits
 	}
 	var (
-		second = fieldWrap(fields[0], seconds)
-		minute = fieldWrap(fields[1], minutes)
-		hour   = fieldWrap(fields[2], hours)
-		dom    = fieldWrap(fields[3], dayOfMonth)
-		month  = fieldWrap(fields[4], months)
-		dow    = fieldWrap(fields[5], dayOfWeek)
+		second = fieldWrap(0, Second, seconds)
+		minute = fieldWrap(1, Minute, minutes)
+		hour   = fieldWrap(2, Hour, hours)
+		dom    = fieldWrap(3, DayOfMonth, dayOfMonth)
+		month  = fieldWrap(4, Month, months)
+		dow    = fieldWrap(5, DayOfWeek, dayOfWeek)
 	)
 	if err != nil {
 		return nil, err
@@ -116,9 +176,69 @@ func (p *SpecParser) Parse(spec string) (Schedule, error) {
 		DayOfMonth: dom,
 		Month:      month,
 		DayOfWeek:  dow,
+		Location:   loc,
 	}, nil
 }
 
+// parseLocation strip the CRON_TZ= or TZ= prefix of spec.
+//
+// It returns nil location if spec has no prefix.
+func parseLocation(spec string) (*time.Location, string, error) {
+	if !strings.HasPrefix(spec, TimeZonePrefix) && !strings.HasPrefix(spec, CronTimeZonePrefix) {
+		return nil, spec, nil
+	}
+	begin := strings.Index(spec, "=") + 1
+	end := strings.IndexAny(spec, " \t")
+	if end < 0 {
+		err := parseErrorf(KindFieldCount, "Missing fields after time zone: %s", spec)
+		err.Token = spec
+		return nil, spec, err
+	}
+	name := spec[begin:end]
+	if name == "" {
+		// time.LoadLocation returns UTC for the empty name.
+		err := parseErrorf(KindTimeZone, "Missing time zone: %s", spec)
+		err.Column = begin
+		return nil, spec, err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		err := parseErrorf(KindTimeZone, "Invalid time zone %s: %s", name, err)
+		err.Token = name
+		err.Column = begin
+		return nil, spec, err
+	}
+	return loc, strings.TrimSpace(spec[end:]), nil
+}
+
+// fieldsIndex splits s around white space like strings.Fields,
+// and returns the byte offset of each field as well.
+func fieldsIndex(s string) ([]string, []int) {
+	var (
+		fields  []string
+		columns []int
+		begin   = -1
+	)
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if begin >= 0 {
+				fields = append(fields, s[begin:i])
+				columns = append(columns, begin)
+				begin = -1
+			}
+			continue
+		}
+		if begin < 0 {
+			begin = i
+		}
+	}
+	if begin >= 0 {
+		fields = append(fields, s[begin:])
+		columns = append(columns, begin)
+	}
+	return fields, columns
+}
+
 func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 	count := 0
 	for _, place := range places {
@@ -127,7 +247,9 @@ func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 		}
 	}
 	if count != len(fields) {
-		return nil, fmt.Errorf("Parser accept %d fields, found %d: %v", count, len(fields), fields)
+		err := parseErrorf(KindFieldCount, "Parser accept %d fields, found %d: %v", count, len(fields), fields)
+		err.Token = strings.Join(fields, " ")
+		return nil, err
 	}
 	normalize := make([]string, len(places))
 	copy(normalize, defaults)
@@ -141,6 +263,20 @@ func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 	return normalize, nil
 }
 
+// normalizeColumns places the columns of fields like normalizeFields,
+// the column of the default field is 0.
+func normalizeColumns(columns []int, options ParseOption) []int {
+	normalize := make([]int, len(places))
+	n := 0
+	for i, place := range places {
+		if place&options > 0 {
+			normalize[i] = columns[n]
+			n++
+		}
+	}
+	return normalize
+}
+
 // * - , /
 // *
 // 1-10
@@ -148,11 +284,25 @@ func normalizeFields(fields []string, options ParseOption) ([]string, error) {
 // 5/3
 // 1-10,3,4
 func getField(field string, b bounds) (uint64, error) {
-	exprs := strings.FieldsFunc(field, func(r rune) bool { return r == ',' })
-	var bits uint64
-	for _, expr := range exprs {
+	var (
+		bits   uint64
+		column int
+	)
+	for _, expr := range strings.Split(field, ",") {
+		begin := column
+		column += len(expr) + 1
+		if expr == "" {
+			continue
+		}
 		bit, err := parseExpr(expr, b)
 		if err != nil {
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				pe.Token = expr
+				pe.Column = begin
+				pe.Min = b.min
+				pe.Max = b.max
+			}
 			return 0, err
 		}
 		bits |= bit
@@ -211,11 +361,15 @@ func parseDescriptor(expr string) (Schedule, error) {
 	if strings.HasPrefix(expr, DescriptorEveryPrefix) {
 		duration, err := time.ParseDuration(expr[len(DescriptorEveryPrefix):])
 		if err != nil {
-			return nil, fmt.Errorf("Parse duration failure: %s", err)
+			err := parseErrorf(KindDescriptor, "Parse duration failure: %s", err)
+			err.Token = expr
+			return nil, err
 		}
 		return Every(duration), nil
 	}
-	return nil, fmt.Errorf("Invalid descriptor: %s", expr)
+	err := parseErrorf(KindDescriptor, "Invalid descriptor: %s", expr)
+	err.Token = expr
+	return nil, err
 }
 
 func parseExpr(expr string, b bounds) (uint64, error) {
@@ -238,7 +392,7 @@ func parseExpr(expr string, b bounds) (uint64, error) {
 	} else {
 		min, err = parseIntOrName(lowToHigh[0], b.names)
 		if err != nil {
-			return 0, err
+			return 0, &ParseError{Kind: KindUnknownName, Err: err}
 		}
 		switch len(lowToHigh) {
 		case 1:
@@ -246,10 +400,10 @@ func parseExpr(expr string, b bounds) (uint64, error) {
 		case 2:
 			max, err = parseIntOrName(lowToHigh[1], b.names)
 			if err != nil {
-				return 0, err
+				return 0, &ParseError{Kind: KindUnknownName, Err: err}
 			}
 		default:
-			return 0, fmt.Errorf("Too many hypends: %s", expr)
+			return 0, parseErrorf(KindSyntax, "Too many hypends: %s", expr)
 		}
 	}
 	switch len(rangeAndStep) {
@@ -258,7 +412,7 @@ func parseExpr(expr string, b bounds) (uint64, error) {
 	case 2:
 		step, err = parseIntOrName(rangeAndStep[1], nil)
 		if err != nil {
-			return 0, err
+			return 0, &ParseError{Kind: KindBadStep, Err: err}
 		}
 		// N/step means N-max/step
 		if singleDigit {
@@ -268,16 +422,16 @@ func parseExpr(expr string, b bounds) (uint64, error) {
 			extra = 0
 		}
 	default:
-		return 0, fmt.Errorf("Too many slashes: %s", expr)
+		return 0, parseErrorf(KindSyntax, "Too many slashes: %s", expr)
 	}
 	if min < b.min || max > b.max {
-		return 0, fmt.Errorf("The effective range is [%d, %d], but got [%d, %d]: %s", b.min, b.max, min, max, expr)
+		return 0, parseErrorf(KindOutOfRange, "The effective range is [%d, %d], but got [%d, %d]: %s", b.min, b.max, min, max, expr)
 	}
 	if min > max {
-		return 0, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", min, max, expr)
+		return 0, parseErrorf(KindBadRange, "Beginning of range (%d) beyond end of range (%d): %s", min, max, expr)
 	}
 	if step == 0 {
-		return 0, fmt.Errorf("The step (0) is invalid: %s", expr)
+		return 0, parseErrorf(KindBadStep, "The step (0) is invalid: %s", expr)
 	}
 	return getBits(min, max, step) | extra, nil
 }
@@ -294,7 +448,7 @@ func getBits(min, max, step uint) uint64 {
 }
 
 func parseIntOrName(expr string, names map[string]uint) (uint, error) {
-	if val, ok := names[expr]; ok {
+	if val, ok := names[strings.ToLower(expr)]; ok {
 		return val, nil
 	}
 	val, err := strconv.ParseUint(expr, 10, 32)
@@ -310,6 +464,9 @@ type Schedule interface {
 
 type SpecSchedule struct {
 	Second, Minute, Hour, DayOfMonth, Month, DayOfWeek uint64
+
+	// Location overrides the location of the time passed to Next, if not nil.
+	Location *time.Location
 }
 
 type bounds struct {
@@ -348,8 +505,50 @@ var (
 )
 
 // Next caculate the next time by spec
+//
+// The spec is matched against the wall clock of the location. On the days of
+// daylight saving transitions:
+//   - a time skipped by the transition runs once, right after the gap;
+//   - a time repeated by the transition runs once, at its first occurrence.
 func (s *SpecSchedule) Next(t time.Time) time.Time {
-	t = t.Add(time.Second).Truncate(time.Second)
+	origLocation := t.Location()
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+	start := t.Add(time.Second).Truncate(time.Second)
+	next := s.next(start)
+	if next.IsZero() {
+		return next
+	}
+	return next.In(origLocation)
+}
+
+// next returns the first time not before start which matches the spec.
+func (s *SpecSchedule) next(start time.Time) time.Time {
+	w := wallClock(start)
+	for {
+		w = s.nextWallClock(w)
+		if w.IsZero() {
+			return w
+		}
+		instants := fromWallClock(w, start.Location())
+		if len(instants) == 0 {
+			if end := gapEnd(w, start.Location()); !end.Before(start) {
+				return end
+			}
+		}
+		// Only the first occurrence of a repeated time counts.
+		if len(instants) > 0 && !instants[0].Before(start) {
+			return instants[0]
+		}
+		w = w.Add(time.Second)
+	}
+}
+
+// nextWallClock returns the first wall clock not before w which matches the spec.
+//
+// Both w and the result are wall clocks in UTC, see wallClock.
+func (s *SpecSchedule) nextWallClock(t time.Time) time.Time {
 	// Prevent leap year
 	maxYear := t.Year() + 5
 
@@ -378,7 +577,6 @@ func (s *SpecSchedule) Next(t time.Time) time.Time {
 				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
 			}
 			t = t.AddDate(0, 0, 1)
-			// TODO: 处理夏令时
 			if t.Day() == 1 {
 				continued = true
 			}
@@ -430,6 +628,60 @@ func (s *SpecSchedule) Next(t time.Time) time.Time {
 	return time.Time{}
 }
 
+// wallClock returns the wall clock of t as a time in UTC,
+// so the arithmetic on it is free of daylight saving transitions.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// fromWallClock returns the times in loc whose wall clock is w, in order.
+//
+// There are two times if w is repeated by a backward transition,
+// and none if w is skipped by a forward transition.
+func fromWallClock(w time.Time, loc *time.Location) []time.Time {
+	var instants []time.Time
+	sec := w.Unix()
+	// The offsets before and after a transition.
+	for _, d := range []int64{-86400, 86400} {
+		_, offset := time.Unix(sec+d, 0).In(loc).Zone()
+		instant := time.Unix(sec-int64(offset), 0).In(loc)
+		if _, o := instant.Zone(); o != offset {
+			continue
+		}
+		switch {
+		case len(instants) == 0:
+			instants = append(instants, instant)
+		case instant.Before(instants[0]):
+			instants = append([]time.Time{instant}, instants...)
+		case instant.After(instants[0]):
+			instants = append(instants, instant)
+		}
+	}
+	return instants
+}
+
+// gapEnd returns the end of the forward transition which skips w.
+func gapEnd(w time.Time, loc *time.Location) time.Time {
+	sec := w.Unix()
+	_, before := time.Unix(sec-86400, 0).In(loc).Zone()
+	_, after := time.Unix(sec+86400, 0).In(loc).Zone()
+	return searchTransition(sec-int64(after), sec-int64(before), loc)
+}
+
+// searchTransition returns the first time in (lo, hi] whose offset differs from lo.
+func searchTransition(lo, hi int64, loc *time.Location) time.Time {
+	_, offset := time.Unix(lo, 0).In(loc).Zone()
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if _, o := time.Unix(mid, 0).In(loc).Zone(); o == offset {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return time.Unix(hi, 0).In(loc)
+}
+
 // dayMatches
 func dayMatches(s *SpecSchedule, t time.Time) bool {
 	var (
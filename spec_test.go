@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -43,6 +44,11 @@ func TestParseIntOrName(t *testing.T) {
 			names:  dayOfWeek.names,
 			result: 3,
 		},
+		{
+			expr:   "WED",
+			names:  dayOfWeek.names,
+			result: 3,
+		},
 		{
 			expr:   "10",
 			names:  nil,
@@ -307,6 +313,323 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseError(t *testing.T) {
+	parser := NewParser(ParseOptionStandard)
+	datas := []struct {
+		spec     string
+		kind     ParseErrorKind
+		field    ParseOption
+		token    string
+		column   int
+		min, max uint
+	}{
+		{
+			spec:   "60 * * * *",
+			kind:   KindOutOfRange,
+			field:  Minute,
+			token:  "60",
+			column: 0,
+			min:    0,
+			max:    59,
+		},
+		{
+			spec:   "0 9 * * mon,fri-sun",
+			kind:   KindBadRange,
+			field:  DayOfWeek,
+			token:  "fri-sun",
+			column: 12,
+			min:    0,
+			max:    6,
+		},
+		{
+			spec:   "  0  0 0 * *",
+			kind:   KindOutOfRange,
+			field:  DayOfMonth,
+			token:  "0",
+			column: 7,
+			min:    1,
+			max:    31,
+		},
+		{
+			spec:   "*/0 * * * *",
+			kind:   KindBadStep,
+			field:  Minute,
+			token:  "*/0",
+			column: 0,
+			min:    0,
+			max:    59,
+		},
+		{
+			spec:   "0 0 * foo *",
+			kind:   KindUnknownName,
+			field:  Month,
+			token:  "foo",
+			column: 6,
+			min:    1,
+			max:    12,
+		},
+		{
+			spec:   "CRON_TZ=UTC 1-2-3 * * * *",
+			kind:   KindSyntax,
+			field:  Minute,
+			token:  "1-2-3",
+			column: 12,
+			min:    0,
+			max:    59,
+		},
+		{
+			spec:   "0 0 * *",
+			kind:   KindFieldCount,
+			token:  "0 0 * *",
+			column: 0,
+		},
+		{
+			spec:   "TZ=UTC @weekday",
+			kind:   KindDescriptor,
+			token:  "@weekday",
+			column: 7,
+		},
+		{
+			spec:   "CRON_TZ=Mars/Olympus 0 9 * * *",
+			kind:   KindTimeZone,
+			token:  "Mars/Olympus",
+			column: 8,
+		},
+		{
+			spec:   "CRON_TZ= 0 9 * * *",
+			kind:   KindTimeZone,
+			column: 8,
+		},
+	}
+	for _, data := range datas {
+		_, err := parser.Parse(data.spec)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("parser.Parse(%s) => *ParseError, but got %v", data.spec, err)
+		}
+		if pe.Spec != data.spec || pe.Kind != data.kind || pe.Field != data.field || pe.Token != data.token || pe.Column != data.column || pe.Min != data.min || pe.Max != data.max {
+			t.Fatalf("parser.Parse(%s) => (%s, %v, %s, %d, [%d, %d]), but got (%s, %v, %s, %d, [%d, %d])",
+				data.spec, data.kind, data.field, data.token, data.column, data.min, data.max,
+				pe.Kind, pe.Field, pe.Token, pe.Column, pe.Min, pe.Max)
+		}
+		if data.spec[pe.Column:pe.Column+len(pe.Token)] != pe.Token {
+			t.Fatalf("parser.Parse(%s) => token %s at column %d, but got %s", data.spec, pe.Token, pe.Column, data.spec[pe.Column:pe.Column+len(pe.Token)])
+		}
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	datas := []struct {
+		spec     string
+		location string
+		rest     string
+		err      string
+	}{
+		{
+			spec: "0 9 * * mon-fri",
+			rest: "0 9 * * mon-fri",
+		},
+		{
+			spec:     "CRON_TZ=Europe/Berlin 0 9 * * mon-fri",
+			location: "Europe/Berlin",
+			rest:     "0 9 * * mon-fri",
+		},
+		{
+			spec:     "TZ=Asia/Shanghai  @daily",
+			location: "Asia/Shanghai",
+			rest:     "@daily",
+		},
+		{
+			spec: "TZ=Asia/Shanghai",
+			err:  "Missing fields after time zone",
+		},
+		{
+			spec: "CRON_TZ=Mars/Olympus 0 9 * * *",
+			err:  "Invalid time zone Mars/Olympus",
+		},
+		{
+			spec: "CRON_TZ= 0 9 * * *",
+			err:  "Missing time zone",
+		},
+	}
+	for _, data := range datas {
+		loc, rest, err := parseLocation(data.spec)
+		if err != nil {
+			if data.err == "" || !strings.Contains(err.Error(), data.err) {
+				t.Fatalf("parseLocation(%s) => (..., ...%s...), but got %s", data.spec, data.err, err)
+			}
+			continue
+		}
+		if data.err != "" {
+			t.Fatalf("parseLocation(%s) => (..., ...%s...), but got nil", data.spec, data.err)
+		}
+		if (loc == nil && data.location != "") || (loc != nil && loc.String() != data.location) || rest != data.rest {
+			t.Fatalf("parseLocation(%s) => (%s, %s, nil), but got (%v, %s, nil)", data.spec, data.location, data.rest, loc, rest)
+		}
+	}
+}
+
+func TestParseWithLocation(t *testing.T) {
+	parser := NewParser(ParseOptionStandard)
+	datas := []struct {
+		spec   string
+		from   time.Time
+		result time.Time
+	}{
+		{
+			// 2021-06-01 is tuesday, 09:00 in Berlin is 07:00 in UTC
+			spec:   "CRON_TZ=Europe/Berlin 0 9 * * MON-FRI",
+			from:   time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC),
+			result: time.Date(2021, 6, 2, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:   "TZ=Asia/Shanghai 0 9 * * *",
+			from:   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			result: time.Date(2021, 6, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:   "CRON_TZ=America/New_York @daily",
+			from:   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			result: time.Date(2021, 6, 1, 4, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, data := range datas {
+		schedule, err := parser.Parse(data.spec)
+		if err != nil {
+			t.Fatalf("parser.Parse(%s) failure: %s", data.spec, err)
+		}
+		next := schedule.Next(data.from)
+		if !next.Equal(data.result) || next.Location() != data.from.Location() {
+			t.Fatalf("schedule(%s).Next(%v) => %v, but got %v", data.spec, data.from, data.result, next)
+		}
+	}
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+	parser := NewParser(ParseOptionStandard)
+	datas := []struct {
+		spec    string
+		zone    string
+		from    string
+		results []string
+	}{
+		// America/New_York: 2021-03-14 02:00 => 03:00
+		{
+			spec:    "30 2 * * *",
+			zone:    "America/New_York",
+			from:    "2021-03-13T12:00:00-05:00",
+			results: []string{"2021-03-14T03:00:00-04:00", "2021-03-15T02:30:00-04:00"},
+		},
+		{
+			spec:    "*/20 2 * * *",
+			zone:    "America/New_York",
+			from:    "2021-03-14T01:00:00-05:00",
+			results: []string{"2021-03-14T03:00:00-04:00", "2021-03-15T02:00:00-04:00"},
+		},
+		{
+			spec:    "30 * * * *",
+			zone:    "America/New_York",
+			from:    "2021-03-14T01:00:00-05:00",
+			results: []string{"2021-03-14T01:30:00-05:00", "2021-03-14T03:00:00-04:00", "2021-03-14T03:30:00-04:00"},
+		},
+		// America/New_York: 2021-11-07 02:00 => 01:00
+		{
+			spec:    "30 1 * * *",
+			zone:    "America/New_York",
+			from:    "2021-11-06T12:00:00-04:00",
+			results: []string{"2021-11-07T01:30:00-04:00", "2021-11-08T01:30:00-05:00"},
+		},
+		{
+			spec:    "30 1 * * *",
+			zone:    "America/New_York",
+			from:    "2021-11-07T01:10:00-05:00",
+			results: []string{"2021-11-08T01:30:00-05:00"},
+		},
+		{
+			spec:    "@hourly",
+			zone:    "America/New_York",
+			from:    "2021-11-07T00:30:00-04:00",
+			results: []string{"2021-11-07T01:00:00-04:00", "2021-11-07T02:00:00-05:00"},
+		},
+		{
+			spec:    "30 * * * *",
+			zone:    "America/New_York",
+			from:    "2021-11-07T00:45:00-04:00",
+			results: []string{"2021-11-07T01:30:00-04:00", "2021-11-07T02:30:00-05:00"},
+		},
+		{
+			spec:    "@daily",
+			zone:    "America/New_York",
+			from:    "2021-11-06T12:00:00-04:00",
+			results: []string{"2021-11-07T00:00:00-04:00", "2021-11-08T00:00:00-05:00"},
+		},
+		// Europe/Berlin: 2021-03-28 02:00 => 03:00
+		{
+			spec:    "30 2 * * *",
+			zone:    "Europe/Berlin",
+			from:    "2021-03-27T12:00:00+01:00",
+			results: []string{"2021-03-28T03:00:00+02:00", "2021-03-29T02:30:00+02:00"},
+		},
+		// Europe/Berlin: 2021-10-31 03:00 => 02:00
+		{
+			spec:    "30 2 * * *",
+			zone:    "Europe/Berlin",
+			from:    "2021-10-30T12:00:00+02:00",
+			results: []string{"2021-10-31T02:30:00+02:00", "2021-11-01T02:30:00+01:00"},
+		},
+		// Australia/Sydney: 2021-04-04 03:00 => 02:00
+		{
+			spec:    "15 2 * * *",
+			zone:    "Australia/Sydney",
+			from:    "2021-04-03T12:00:00+11:00",
+			results: []string{"2021-04-04T02:15:00+11:00", "2021-04-05T02:15:00+10:00"},
+		},
+		// Australia/Sydney: 2021-10-03 02:00 => 03:00
+		{
+			spec:    "15 2 * * *",
+			zone:    "Australia/Sydney",
+			from:    "2021-10-02T12:00:00+10:00",
+			results: []string{"2021-10-03T03:00:00+11:00", "2021-10-04T02:15:00+11:00"},
+		},
+		// Australia/Lord_Howe: 2021-04-04 02:00 => 01:30
+		{
+			spec:    "45 1 * * *",
+			zone:    "Australia/Lord_Howe",
+			from:    "2021-04-03T12:00:00+11:00",
+			results: []string{"2021-04-04T01:45:00+11:00", "2021-04-05T01:45:00+10:30"},
+		},
+		// Australia/Lord_Howe: 2021-10-03 02:00 => 02:30
+		{
+			spec:    "15 2 * * *",
+			zone:    "Australia/Lord_Howe",
+			from:    "2021-10-02T12:00:00+10:30",
+			results: []string{"2021-10-03T02:30:00+11:00", "2021-10-04T02:15:00+11:00"},
+		},
+	}
+	for _, data := range datas {
+		loc, err := time.LoadLocation(data.zone)
+		if err != nil {
+			t.Fatalf("load location failure: %s", err)
+		}
+		schedule, err := parser.Parse(data.spec)
+		if err != nil {
+			t.Fatalf("build schedule failure: %s", err)
+		}
+		ti, err := time.Parse(time.RFC3339, data.from)
+		if err != nil {
+			t.Fatalf("parse time err: %s", err)
+		}
+		ti = ti.In(loc)
+		for _, r := range data.results {
+			next := schedule.Next(ti)
+			if next.Format(time.RFC3339) != r {
+				t.Fatalf("schedule(%s).Next(%v) in %s => %s, but got %s", data.spec, ti.Format(time.RFC3339), data.zone, r, next.Format(time.RFC3339))
+			}
+			ti = next
+		}
+	}
+}
+
 func compareStringSlice(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
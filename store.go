@@ -0,0 +1,388 @@
+package cron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EntryRecord is the metadata of an entry saved in a Store.
+type EntryRecord struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Spec        string            `json:"spec"`
+	JobType     string            `json:"job_type"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Prev        time.Time         `json:"prev"`
+	Next        time.Time         `json:"next"`
+	Times       uint              `json:"times,omitempty"`
+	Count       uint              `json:"count"`
+	Paused      bool              `json:"paused,omitempty"`
+	LastSuccess time.Time         `json:"last_success"`
+}
+
+// Store saves the metadata of entries, so that they are restored after the
+// process restarts, see WithStore and Cron.Restore.
+//
+// The entries with a name and a job type are saved, they are keyed by name.
+// The records are written in background, the changes of an entry within
+// storeDelay are written once.
+type Store interface {
+	// Save saves the record, or replaces the one with the same name.
+	Save(r EntryRecord) error
+	// Load returns all records.
+	Load() ([]EntryRecord, error)
+	// Delete deletes the record with the name.
+	Delete(name string) error
+}
+
+// BatchStore is a Store which applies the changes of entries at once,
+// they are applied by Apply instead of Save and Delete if it is implemented.
+type BatchStore interface {
+	Store
+	// Apply saves the records, and deletes the records with the names.
+	Apply(saved []EntryRecord, deleted []string) error
+}
+
+// JobFactory creates the job of a job type.
+type JobFactory func() Job
+
+// Registry maps the job types to the factories of jobs,
+// which reconstruct the entries restored from a Store.
+//
+// It is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]registered
+}
+
+type registered struct {
+	factory JobFactory
+	opts    []EntryOption
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]registered),
+	}
+}
+
+// Register the factory of the job type, and the options applied to the
+// entries of the job type restored from a Store.
+func (r *Registry) Register(jobType string, factory JobFactory, opts ...EntryOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[jobType] = registered{factory, opts}
+}
+
+// lookup returns the factory and the options of the job type.
+func (r *Registry) lookup(jobType string) (registered, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.factories[jobType]
+	return f, ok
+}
+
+// newEntryRecord returns the record of entry.
+func newEntryRecord(e *Entry) EntryRecord {
+	return EntryRecord{
+		ID:          e.ID,
+		Name:        e.Name,
+		Spec:        e.Spec,
+		JobType:     e.JobType,
+		Labels:      e.Labels,
+		Prev:        e.Prev,
+		Next:        e.Next,
+		Times:       e.Times,
+		Count:       e.count,
+		Paused:      e.Paused,
+		LastSuccess: e.LastSuccess(),
+	}
+}
+
+// storeDelay is the delay of writing the changes of entries to the Store.
+const storeDelay = time.Second
+
+// storeWriter writes the records of entries to a Store in background,
+// so that the Cron is not blocked by the Store.
+//
+// The changes of an entry within storeDelay are merged, and the time of the
+// last success is read from the entry when it is written.
+type storeWriter struct {
+	store  Store
+	logger Logger
+
+	mu      sync.Mutex
+	pending map[string]*storedEntry // nil to delete the record
+	saved   map[string]*storedEntry // the last saved ones, by name
+	timer   *time.Timer
+
+	writing sync.Mutex // held while writing, so that the writes are ordered
+}
+
+type storedEntry struct {
+	record EntryRecord
+	state  *entryState
+}
+
+func newStoreWriter(store Store, logger Logger) *storeWriter {
+	return &storeWriter{
+		store:   store,
+		logger:  logger,
+		pending: make(map[string]*storedEntry),
+		saved:   make(map[string]*storedEntry),
+	}
+}
+
+// save writes the record of entry later.
+func (w *storeWriter) save(e *Entry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stored := &storedEntry{newEntryRecord(e), e.state}
+	w.saved[e.Name] = stored
+	w.set(e.Name, stored)
+}
+
+// touch writes the last saved record of the name later,
+// after its job finishes.
+func (w *storeWriter) touch(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stored, ok := w.saved[name]; ok {
+		w.set(name, stored)
+	}
+}
+
+// delete deletes the record of the name later.
+func (w *storeWriter) delete(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.saved, name)
+	w.set(name, nil)
+}
+
+// set sets the pending change of the name, w.mu is held by the caller.
+func (w *storeWriter) set(name string, stored *storedEntry) {
+	w.pending[name] = stored
+	if w.timer == nil {
+		w.timer = time.AfterFunc(storeDelay, w.flush)
+	}
+}
+
+// flush writes the pending changes now.
+func (w *storeWriter) flush() {
+	w.writing.Lock()
+	defer w.writing.Unlock()
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]*storedEntry)
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var (
+		saved   []EntryRecord
+		deleted []string
+	)
+	for _, name := range names {
+		stored := pending[name]
+		if stored == nil {
+			deleted = append(deleted, name)
+			continue
+		}
+		r := stored.record
+		stored.state.mu.Lock()
+		if stored.state.lastSuccess.After(r.LastSuccess) {
+			r.LastSuccess = stored.state.lastSuccess
+		}
+		stored.state.mu.Unlock()
+		saved = append(saved, r)
+	}
+
+	if batch, ok := w.store.(BatchStore); ok {
+		if err := batch.Apply(saved, deleted); err != nil {
+			w.logger.Error("Save entries failure, saved: %d, deleted: %d: %s", len(saved), len(deleted), err)
+		}
+		return
+	}
+	for _, name := range deleted {
+		if err := w.store.Delete(name); err != nil {
+			w.logger.Error("Delete entry failure, name: %s: %s", name, err)
+		}
+	}
+	for _, r := range saved {
+		if err := w.store.Save(r); err != nil {
+			w.logger.Error("Save entry failure, id: %d, name: %s: %s", r.ID, r.Name, err)
+		}
+	}
+}
+
+// MemoryStore is a Store in memory, it is mostly used in tests.
+//
+// It is safe for concurrent use by multiple goroutines.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]EntryRecord
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]EntryRecord),
+	}
+}
+
+func (s *MemoryStore) Save(r EntryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.Name] = r
+	return nil
+}
+
+func (s *MemoryStore) Load() ([]EntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedRecords(s.records), nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, name)
+	return nil
+}
+
+func (s *MemoryStore) Apply(saved []EntryRecord, deleted []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range deleted {
+		delete(s.records, name)
+	}
+	for _, r := range saved {
+		s.records[r.Name] = r
+	}
+	return nil
+}
+
+// FileStore is a Store in a JSON file.
+//
+// The records are kept in memory, and the file is replaced on every change.
+// It is safe for concurrent use by multiple goroutines, but not by multiple
+// processes.
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]EntryRecord
+}
+
+// NewFileStore returns a FileStore in the file of path,
+// and loads the records in the file if it exists.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		records: make(map[string]EntryRecord),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []EntryRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("Invalid store file %s: %w", path, err)
+	}
+	for _, r := range records {
+		s.records[r.Name] = r
+	}
+	return s, nil
+}
+
+func (s *FileStore) Save(r EntryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.Name] = r
+	return s.flush()
+}
+
+func (s *FileStore) Load() ([]EntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedRecords(s.records), nil
+}
+
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[name]; !ok {
+		return nil
+	}
+	delete(s.records, name)
+	return s.flush()
+}
+
+// Apply applies the changes, and replaces the file once.
+func (s *FileStore) Apply(saved []EntryRecord, deleted []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range deleted {
+		delete(s.records, name)
+	}
+	for _, r := range saved {
+		s.records[r.Name] = r
+	}
+	return s.flush()
+}
+
+// flush replaces the file with the records atomically.
+func (s *FileStore) flush() error {
+	data, err := json.MarshalIndent(sortedRecords(s.records), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		// the data must be on disk before the rename is
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// sortedRecords returns the records ordered by name.
+func sortedRecords(records map[string]EntryRecord) []EntryRecord {
+	result := make([]EntryRecord, 0, len(records))
+	for _, r := range records {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,185 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore(%s) failure: %s", path, err)
+	}
+	prev := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	records := []EntryRecord{
+		{ID: 1, Name: "clean", Spec: "@daily", JobType: "clean", Prev: prev, Count: 3},
+		{ID: 2, Name: "report", Spec: "0 * * * *", JobType: "report", Labels: map[string]string{"tenant": "a"}, Paused: true},
+	}
+	for _, r := range records {
+		if err := s.Save(r); err != nil {
+			t.Fatalf("Save(%s) failure: %s", r.Name, err)
+		}
+	}
+	if err := s.Save(EntryRecord{ID: 3, Name: "temp", Spec: "@hourly", JobType: "temp"}); err != nil {
+		t.Fatalf("Save(temp) failure: %s", err)
+	}
+	if err := s.Delete("temp"); err != nil {
+		t.Fatalf("Delete(temp) failure: %s", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore(%s) again failure: %s", path, err)
+	}
+	loaded, err := reopened.Load()
+	if err != nil {
+		t.Fatalf("Load() failure: %s", err)
+	}
+	if !reflect.DeepEqual(loaded, records) {
+		t.Fatalf("Load() => %+v, but got %+v", records, loaded)
+	}
+
+	temp := EntryRecord{ID: 3, Name: "temp", Spec: "@hourly", JobType: "temp"}
+	if err := s.Apply([]EntryRecord{temp}, []string{"clean"}); err != nil {
+		t.Fatalf("Apply(temp, -clean) failure: %s", err)
+	}
+	reopened, _ = NewFileStore(path)
+	loaded, _ = reopened.Load()
+	if expected := []EntryRecord{records[1], temp}; !reflect.DeepEqual(loaded, expected) {
+		t.Fatalf("Load() after Apply => %+v, but got %+v", expected, loaded)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	var count int32
+	store := NewMemoryStore()
+	registry := NewRegistry()
+	registry.Register("count", func() Job {
+		return FuncJob(func() { atomic.AddInt32(&count, 1) })
+	})
+
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithStore(store, registry))
+	if err := h.Restore(); err != nil {
+		t.Fatalf("Restore() with empty store => nil, but got %s", err)
+	}
+	h.AddFunc("1h", func() {}, WithEntryName("skip"))
+	id := h.AddFunc("5ms", func() { atomic.AddInt32(&count, 1) },
+		WithEntryName("count"), WithEntryJobType("count"), WithEntryLabels(map[string]string{"tenant": "a"}))
+	removed := h.AddFunc("1h", func() {}, WithEntryName("removed"), WithEntryJobType("count"))
+	h.Remove(removed)
+	go h.Run()
+	time.Sleep(30 * time.Millisecond)
+	h.Shutdown(context.Background())
+	before, _ := h.Entry(id)
+
+	records, _ := store.Load()
+	if len(records) != 1 || records[0].Name != "count" || records[0].Count != before.Count() {
+		t.Fatalf("Load() => the record of count, but got %+v", records)
+	}
+	store.Save(EntryRecord{Name: "unknown", Spec: "1h", JobType: "unknown"})
+
+	restored := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithStore(store, registry))
+	if err := restored.Restore(); !errors.Is(err, ErrUnknownJobType) {
+		t.Fatalf("Restore() => %v, but got %v", ErrUnknownJobType, err)
+	}
+	e, ok := restored.EntryByName("count")
+	if !ok || e.ID != id || e.Count() != before.Count() || !e.Prev.Equal(before.Prev) ||
+		!e.Next.Equal(before.Next) || !reflect.DeepEqual(e.Labels, before.Labels) || e.LastSuccess().IsZero() {
+		t.Fatalf("EntryByName(count) => %+v, but got (%v, %+v)", before, ok, e)
+	}
+
+	// the restored counts are merged into the added entry
+	merged := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithStore(store, registry))
+	merged.AddFunc("5ms", func() {}, WithEntryName("other"))
+	added := merged.AddFunc("5ms", func() {}, WithEntryName("count"), WithEntryJobType("count"))
+	merged.Restore()
+	if e, _ := merged.Entry(added); e.Count() != before.Count() || !e.Next.Equal(before.Next) {
+		t.Fatalf("Entry(%d) => (count %d, next %v), but got (%d, %v)", added, before.Count(), before.Next, e.Count(), e.Next)
+	}
+}
+
+func TestRestoreWithoutStore(t *testing.T) {
+	h := New(WithLogger(printfLogger(testPrintf{t})))
+	if err := h.Restore(); !errors.Is(err, ErrNoStore) {
+		t.Fatalf("Restore() => %v, but got %v", ErrNoStore, err)
+	}
+}
+
+// countingStore counts the saves of records, it is not a BatchStore.
+type countingStore struct {
+	Store
+	saves int32
+}
+
+func (s *countingStore) Save(r EntryRecord) error {
+	atomic.AddInt32(&s.saves, 1)
+	return s.Store.Save(r)
+}
+
+// countingBatchStore counts the batches of changes.
+type countingBatchStore struct {
+	*MemoryStore
+	saves   int32
+	applies int32
+}
+
+func (s *countingBatchStore) Save(r EntryRecord) error {
+	atomic.AddInt32(&s.saves, 1)
+	return s.MemoryStore.Save(r)
+}
+
+func (s *countingBatchStore) Apply(saved []EntryRecord, deleted []string) error {
+	atomic.AddInt32(&s.applies, 1)
+	return s.MemoryStore.Apply(saved, deleted)
+}
+
+func TestStoreWriter(t *testing.T) {
+	store := &countingStore{Store: NewMemoryStore()}
+	registry := NewRegistry()
+	registry.Register("tick", func() Job { return FuncJob(func() {}) })
+
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithStore(store, registry))
+	id := h.AddFunc("5ms", func() {}, WithEntryName("tick"), WithEntryJobType("tick"))
+	go h.Run()
+	time.Sleep(50 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	// the firings within the delay are written when the Cron stops,
+	// and again if a job finishes after that
+	if saves := atomic.LoadInt32(&store.saves); saves < 1 || saves > 2 {
+		t.Fatalf("saves of entry firing every 5ms in 50ms => 1 or 2, but got %d", saves)
+	}
+	e, _ := h.Entry(id)
+	records, _ := store.Load()
+	if len(records) != 1 || records[0].Count != e.Count() || !records[0].LastSuccess.Equal(e.LastSuccess()) {
+		t.Fatalf("Load() => count %d, last success %s, but got %+v", e.Count(), e.LastSuccess(), records)
+	}
+}
+
+func TestStoreWriterBatch(t *testing.T) {
+	store := &countingBatchStore{MemoryStore: NewMemoryStore()}
+	registry := NewRegistry()
+	registry.Register("tick", func() Job { return FuncJob(func() {}) })
+
+	h := New(WithParser(intervalParser{}), WithLogger(printfLogger(testPrintf{t})), WithStore(store, registry))
+	for _, name := range []string{"a", "b", "c"} {
+		h.AddFunc("5ms", func() {}, WithEntryName(name), WithEntryJobType("tick"))
+	}
+	go h.Run()
+	time.Sleep(50 * time.Millisecond)
+	h.Shutdown(context.Background())
+
+	// the changes of all entries are applied at once
+	if saves, applies := atomic.LoadInt32(&store.saves), atomic.LoadInt32(&store.applies); saves != 0 || applies < 1 || applies > 2 {
+		t.Fatalf("writes of 3 entries => (0 saves, 1 or 2 applies), but got (%d, %d)", saves, applies)
+	}
+	if records, _ := store.Load(); len(records) != 3 {
+		t.Fatalf("Load() => 3 records, but got %+v", records)
+	}
+}
@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"sort"
+	"sync"
+)
+
+// jobTracker tracks the running jobs by entry-id.
+//
+// It is safe for concurrent use by multiple goroutines.
+type jobTracker struct {
+	mu      sync.Mutex
+	running map[int]int   // entry-id => the count of running jobs
+	total   int           // the count of all running jobs
+	idle    chan struct{} // closed if no job is running
+}
+
+func newJobTracker() *jobTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &jobTracker{
+		running: make(map[int]int),
+		idle:    idle,
+	}
+}
+
+// start marks a job of entry is running.
+func (t *jobTracker) start(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.total == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.total++
+	t.running[id]++
+}
+
+// done marks a job of entry is finished.
+func (t *jobTracker) done(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total--
+	if t.running[id]--; t.running[id] <= 0 {
+		delete(t.running, id)
+	}
+	if t.total == 0 {
+		close(t.idle)
+	}
+}
+
+// wait returns a channel that's closed when no job is running.
+func (t *jobTracker) wait() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.idle
+}
+
+// ids returns the sorted entry-ids of the running jobs.
+func (t *jobTracker) ids() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]int, 0, len(t.running))
+	for id := range t.running {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}